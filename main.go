@@ -2,17 +2,26 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"slices"
 	"strings"
+	"syscall"
 
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
 	"github.com/TrueBlocks/create-local-app/pkg/config"
 	"github.com/TrueBlocks/create-local-app/pkg/customize"
+	"github.com/TrueBlocks/create-local-app/pkg/generator"
+	"github.com/TrueBlocks/create-local-app/pkg/lint"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
 	"github.com/TrueBlocks/create-local-app/pkg/processor"
 	"github.com/TrueBlocks/create-local-app/pkg/templates"
 	"github.com/TrueBlocks/trueblocks-chifra/v6/pkg/colors"
@@ -25,6 +34,9 @@ var systemTemplatesFS embed.FS
 //go:embed VERSION
 var versionContent string
 
+// stdin is shared by every interactive prompt so buffered input is never lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
 func main() {
 	version := strings.TrimSpace(versionContent)
 	built := file.MustGetLatestFileTime("VERSION")
@@ -34,6 +46,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// With --json only the plan goes to stdout, so progress messages go to stderr
+	stdout := os.Stdout
+	if args.IsJSON {
+		os.Stdout = os.Stderr
+	}
+
 	// Initialize user configuration directory structure
 	if err := config.InitializeUserConfig(); err != nil {
 		fmt.Printf("Error initializing user config: %v\n", err)
@@ -64,6 +82,46 @@ func main() {
 		return
 	}
 
+	// Handle template commands
+	if args.TemplateCommand != "" {
+		var err error
+		switch args.TemplateCommand {
+		case "verify":
+			err = verifyTemplate(args.TemplateName)
+		case "lint":
+			err = lintTemplate(args.TemplateName)
+		case "install":
+			err = installTemplate(args.TemplateSource, args.TemplateName)
+		case "update":
+			err = updateTemplate(args.TemplateName)
+		case "export":
+			err = exportTemplate(args.TemplateName, args.OutputFile, version)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle update mode
+	if args.IsUpdate {
+		if err := updateProject(version); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle restore mode
+	if args.IsRestore {
+		if err := restoreBackup(args.BackupID); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle customize mode
 	if args.IsCustomize {
 		if err := customize.RunCustomize(); err != nil {
@@ -80,7 +138,10 @@ func main() {
 	}
 
 	if !args.IsCreate && !args.IsRemove {
-		checkLocalFolder(projectDir, args) // may not return
+		// A dry run writes nothing, so it may look at a folder that already has files
+		if !args.IsDryRun {
+			checkLocalFolder(projectDir, args) // may not return
+		}
 
 	} else {
 		wailsJsonPath := filepath.Join(projectDir, "wails.json")
@@ -122,92 +183,11 @@ func main() {
 		}
 	}
 
-	if args.IsAuto {
-		if appConfig.Organization == "" || appConfig.ProjectName == "" || appConfig.Github == "" || appConfig.Domain == "" {
-			fmt.Println("Error: Auto mode requires default values in config file.")
-			fmt.Println("Run without --auto first to create config file with defaults.")
-			os.Exit(1)
-		}
+	features := appConfig.Features
+	if args.HasFeatures {
+		features = args.Features
 	}
 
-	organization := appConfig.Organization
-	projectName := appConfig.ProjectName
-	github := appConfig.Github
-	domain := appConfig.Domain
-	chifra := "github.com/TrueBlocks/trueblocks-chifra/v6"
-
-	// Interactive prompting for missing values (regular mode or --create mode or developer mode)
-	// For --create mode, always prompt to allow project-specific configuration
-	shouldPrompt := (!args.IsRemove && !args.IsAuto) &&
-		((!args.IsCreate) || args.IsCreate || developerMode)
-
-	if shouldPrompt {
-		reader := bufio.NewReader(os.Stdin)
-
-		fmt.Printf("Organization [%s]: ", appConfig.Organization)
-		organizationInput, _ := reader.ReadString('\n')
-		organizationInput = strings.TrimSpace(organizationInput)
-		if organizationInput != "" {
-			organization = organizationInput
-		}
-
-		fmt.Printf("Project Name [%s]: ", appConfig.ProjectName)
-		projectNameInput, _ := reader.ReadString('\n')
-		projectNameInput = strings.TrimSpace(projectNameInput)
-		if projectNameInput != "" {
-			projectName = projectNameInput
-		}
-
-		fmt.Printf("Github [%s]: ", appConfig.Github)
-		githubInput, _ := reader.ReadString('\n')
-		githubInput = strings.TrimSpace(githubInput)
-		if githubInput != "" {
-			github = githubInput
-		}
-
-		fmt.Printf("Domain [%s]: ", appConfig.Domain)
-		domainInput, _ := reader.ReadString('\n')
-		domainInput = strings.TrimSpace(domainInput)
-		if domainInput != "" {
-			domain = domainInput
-		}
-	} else {
-		if args.IsCreate {
-			fmt.Println("Running in create template mode with default values:")
-		} else {
-			fmt.Println("Running in auto mode with default values:")
-		}
-		fmt.Println("Organization:", organization)
-		fmt.Println("Project Name:", projectName)
-		fmt.Println("Github:", github)
-		fmt.Println("Domain:", domain)
-	}
-
-	// Validate required fields
-	if organization == "" {
-		fmt.Println("Error: Organization is required.")
-		os.Exit(1)
-	}
-	if projectName == "" {
-		fmt.Println("Error: Project Name is required.")
-		os.Exit(1)
-	}
-	if github == "" {
-		fmt.Println("Error: Github is required.")
-		os.Exit(1)
-	}
-	if domain == "" {
-		fmt.Println("Error: Domain is required.")
-		os.Exit(1)
-	}
-
-	publisherName := "YourCompany"
-	publisherEmail := "your_email@your_company.com"
-
-	parts := strings.Split(organization, ",")
-	orgName := strings.TrimSpace(parts[0])
-	slug := strings.ToLower(orgName) + "-" + projectName
-
 	// Determine which template to use and resolve template name for saving in config
 	var resolvedTemplateName string
 
@@ -232,50 +212,16 @@ func main() {
 		resolvedTemplateName = args.TemplateName
 	}
 
-	// Save config if we prompted for values or if template was explicitly specified
-	if shouldPrompt || resolvedTemplateName != "" {
-		// Preserve existing config values and only update what has changed
-		newConfig := &config.Config{
-			Organization:  organization,
-			ProjectName:   projectName,
-			Github:        github,
-			Domain:        domain,
-			Template:      resolvedTemplateName,
-			PreserveFiles: appConfig.PreserveFiles, // Preserve existing PreserveFiles
-			ViewConfig:    appConfig.ViewConfig,    // Preserve existing ViewConfig
-		}
-
-		if args.IsCreate {
-			// In create mode, save to project-local config
-			if err := config.SaveProjectConfig(newConfig); err != nil {
-				fmt.Println("Failed to save project config file:", err)
-				os.Exit(1)
-			}
-		} else {
-			// In regular mode, save to project-local config to establish project-specific settings
-			// This ensures each project gets its own config file
-			if err := config.SaveProjectConfig(newConfig); err != nil {
-				fmt.Println("Failed to save project config file:", err)
-				os.Exit(1)
-			}
-			// Also update global config for convenience as fallback defaults
-			if err := config.SaveGlobalConfig(newConfig); err != nil {
-				fmt.Println("Failed to save global config file:", err)
-				os.Exit(1)
-			}
-		}
-	}
-
 	// Get template directory
 	var templateDir string
+	manifestDir := ""
 	if args.IsCreate {
 		// In create mode, we write to the contributed template directory
-		configDir, err := config.GetUserConfigDir()
+		templateDir, manifestDir, err = templates.CreateDir(args.TemplateName, args.Extends, args.IsDryRun, args.IsForce)
 		if err != nil {
-			fmt.Println("Failed to get user config directory:", err)
+			fmt.Println("Error:", err)
 			os.Exit(1)
 		}
-		templateDir = filepath.Join(configDir, "templates", "contributed", args.TemplateName)
 		fmt.Println("Creating template at:", templateDir)
 	} else {
 		var templateSource string
@@ -292,6 +238,10 @@ func main() {
 			if resolvedDir, err := templates.GetTemplateDir(templateSource); err == nil {
 				templateDir = resolvedDir
 				fmt.Printf("Using template '%s' from: %s\n", templateSource, templateDir)
+			} else if args.UseTemplate != "" && strings.Contains(templateSource, "@") {
+				// A pinned version that isn't installed is not a path
+				fmt.Println("Error:", err)
+				os.Exit(1)
 			} else {
 				// Fall back to treating it as a full path
 				templateDir, err = filepath.Abs(templateSource)
@@ -317,64 +267,201 @@ func main() {
 		}
 	}
 
-	fmt.Println("TEMPLATE_DIR: ", templateDir)
-	fmt.Println("PROJECT_DIR:  ", projectDir)
-	fmt.Println("ORGANIZATION: ", organization)
-	fmt.Println("ORG_NAME:     ", orgName)
-	fmt.Println("SLUG:         ", slug)
-	fmt.Println("PROJECT_NAME: ", projectName)
-	fmt.Println("GITHUB:       ", github)
-	fmt.Println("DOMAIN:       ", domain)
-	fmt.Println("CHIFRA:       ", chifra)
-
-	// Create template variables with safety checks for empty strings
-	projectProper := projectName
-	if len(projectName) > 0 {
-		projectProper = strings.ToUpper(projectName[0:1]) + projectName[1:]
-	}
-
-	templateVars := &processor.TemplateVars{
-		ProjectName:    projectName,
-		ProjectProper:  projectProper,
-		PublisherName:  publisherName,
-		PublisherEmail: publisherEmail,
-		Organization:   organization,
-		OrgName:        orgName,
-		OrgLower:       strings.ToLower(orgName),
-		Slug:           slug,
-		Github:         github,
-		Domain:         domain,
-		Chifra:         chifra,
+	// The template's manifest, combined with those of the templates it extends, decides
+	// which values we prompt for, validate and save. A new version of a template is
+	// described by the manifest of the version before it.
+	if manifestDir == "" {
+		manifestDir = templateDir
+	}
+	templateManifest, err := templates.LoadManifest(manifestDir)
+	if err != nil {
+		fmt.Println("Failed to load template manifest:", err)
+		os.Exit(1)
 	}
+	promptedVars := templateManifest.Prompted()
 
-	if !args.IsCreate && !args.IsRemove {
-		err = filepath.Walk(templateDir, func(path string, info fs.FileInfo, err error) error {
-			if err != nil {
-				return err
+	if args.IsAuto {
+		for _, v := range promptedVars {
+			if v.Required && appConfig.GetValue(v.Name) == "" && v.Default == "" {
+				fmt.Println("Error: Auto mode requires default values in config file.")
+				fmt.Println("Run without --auto first to create config file with defaults.")
+				os.Exit(1)
 			}
+		}
+	}
 
-			relPath, _ := filepath.Rel(templateDir, path)
-			targetPath := filepath.Join(projectDir, relPath)
+	// Preserve existing config values and only update what has changed
+	newConfig := &config.Config{
+		Organization:   appConfig.Organization,
+		PublisherName:  appConfig.PublisherName,
+		PublisherEmail: appConfig.PublisherEmail,
+		ProjectName:    appConfig.ProjectName,
+		Github:         appConfig.Github,
+		Domain:         appConfig.Domain,
+		Template:       resolvedTemplateName,
+		Features:       features,
+		PreserveFiles:  appConfig.PreserveFiles, // Preserve existing PreserveFiles
+		ViewConfig:     appConfig.ViewConfig,    // Preserve existing ViewConfig
+	}
+	for k, v := range appConfig.Variables {
+		newConfig.SetValue(k, v)
+	}
+	for _, v := range promptedVars {
+		if newConfig.GetValue(v.Name) == "" && v.Default != "" {
+			newConfig.SetValue(v.Name, v.Default)
+		}
+	}
+	if newConfig.PublisherName == "" || newConfig.PublisherEmail == "" {
+		// Publisher details default to the user's git identity
+		gitName, gitEmail := config.GitPublisher()
+		if newConfig.PublisherName == "" {
+			newConfig.PublisherName = gitName
+		}
+		if newConfig.PublisherEmail == "" {
+			newConfig.PublisherEmail = gitEmail
+		}
+	}
 
-			if info.IsDir() {
-				return os.MkdirAll(targetPath, os.ModePerm)
-			}
+	// Interactive prompting for missing values (regular mode or --create mode or developer mode)
+	// For --create mode, always prompt to allow project-specific configuration
+	shouldPrompt := (!args.IsRemove && !args.IsAuto) &&
+		((!args.IsCreate) || args.IsCreate || developerMode)
 
-			if processor.ShouldPreserve(relPath, appConfig) {
-				if _, err := os.Stat(targetPath); err == nil {
-					fmt.Printf("Preserving existing file: %s\n", relPath)
-					return nil
-				}
+	if shouldPrompt {
+		for _, v := range promptedVars {
+			fmt.Printf("%s [%s]: ", v.Label(), newConfig.GetValue(v.Name))
+			input, _ := stdin.ReadString('\n')
+			input = strings.TrimSpace(input)
+			if input != "" {
+				newConfig.SetValue(v.Name, input)
 			}
+		}
+	} else {
+		if args.IsCreate {
+			fmt.Println("Running in create template mode with default values:")
+		} else {
+			fmt.Println("Running in auto mode with default values:")
+		}
+		for _, v := range promptedVars {
+			fmt.Printf("%s: %s\n", v.Label(), newConfig.GetValue(v.Name))
+		}
+	}
 
-			input, err := os.ReadFile(path)
-			if err != nil {
-				return err
+	// Validate required fields and patterns
+	for _, v := range promptedVars {
+		if err := v.Check(newConfig.GetValue(v.Name)); err != nil {
+			fmt.Printf("Error: %v.\n", err)
+			os.Exit(1)
+		}
+	}
+
+	templateVars, err := generator.NewTemplateVars(newConfig, templateManifest)
+	if err != nil {
+		fmt.Printf("Error: %v.\n", err)
+		os.Exit(1)
+	}
+
+	// Every project file a generation replaces is backed up first so restore can undo it
+	var bak *backup.Backup
+	if !args.IsCreate && !args.IsDryRun {
+		bak = backup.New(projectDir, "generate")
+	}
+
+	// Save config if we prompted for values or if template was explicitly specified. A
+	// generated project's config is saved along with its files, once they are in place.
+	var projectConfig *config.Config
+	if !args.IsDryRun && (shouldPrompt || resolvedTemplateName != "") {
+		projectConfig = newConfig
+	}
+	if projectConfig != nil && args.IsCreate {
+		// In create mode, save to project-local config
+		if err := config.SaveProjectConfig(newConfig); err != nil {
+			fmt.Println("Failed to save project config file:", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("TEMPLATE_DIR: ", templateDir)
+	fmt.Println("PROJECT_DIR:  ", projectDir)
+	fmt.Println("ORGANIZATION: ", templateVars.Organization)
+	fmt.Println("ORG_NAME:     ", templateVars.OrgName)
+	fmt.Println("PUBLISHER:    ", templateVars.PublisherName, "<"+templateVars.PublisherEmail+">")
+	fmt.Println("SLUG:         ", templateVars.Slug)
+	fmt.Println("PROJECT_NAME: ", templateVars.ProjectName)
+	fmt.Println("GITHUB:       ", templateVars.Github)
+	fmt.Println("DOMAIN:       ", templateVars.Domain)
+	fmt.Println("CHIFRA:       ", templateVars.Chifra)
+	if len(features) > 0 {
+		fmt.Println("FEATURES:     ", strings.Join(features, ","))
+	}
+	for _, name := range slices.Sorted(maps.Keys(templateVars.Extra)) {
+		fmt.Printf("%-14s %s\n", name+":", templateVars.Extra[name])
+	}
+
+	// A dry run records what would happen to each file instead of writing it
+	var plan *generator.Plan
+	if args.IsDryRun {
+		mode := "generate"
+		if args.IsCreate {
+			mode = "create"
+		}
+		plan = &generator.Plan{
+			Mode:        mode,
+			TemplateDir: templateDir,
+			ProjectDir:  projectDir,
+			Variables:   templateVars.Tokens(),
+			Features:    features,
+		}
+	}
+
+	report := &processor.FileReport{}
+	if !args.IsCreate && !args.IsRemove {
+		if !args.IsAuto && !args.IsDryRun {
+			if err := generator.RunHooks("preGenerate", templateManifest.Hooks.PreGenerate, projectDir, templateVars); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
 			}
+		}
+		if !args.IsDryRun {
+			warnModified(projectDir)
+		}
 
-			content := processor.ApplyTemplateVars(string(input), templateVars)
-			return os.WriteFile(targetPath, []byte(content), info.Mode())
-		})
+		// Ctrl-C during generation abandons the staged files instead of killing the process
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		changed := false
+		if plan != nil {
+			report, err = generator.Generate(ctx, templateDir, projectDir, templateVars, appConfig, nil, plan, nil)
+		} else {
+			// The rendered template is the base that a later update merges against
+			lock := generator.NewLock(version, resolvedTemplateName, templateDir, templateVars)
+			report, changed, err = generateProject(ctx, templateDir, projectDir, templateVars, appConfig, projectConfig, lock, bak)
+		}
+		stop()
+		if err != nil && changed {
+			// The files are in place, so the backup is kept to undo them
+			if err := bak.Close(); err != nil {
+				fmt.Println("Warning:", err)
+			}
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			// The staged files were rolled back, so there is nothing to undo
+			if err := bak.Discard(); err != nil {
+				fmt.Println("Warning:", err)
+			}
+		}
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("Interrupted: no files were changed in", projectDir)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Println("Error processing files:", err)
+			if plan == nil {
+				fmt.Println("No files were changed in", projectDir)
+			}
+			os.Exit(1)
+		}
 	} else {
 		// Set environment variable to prevent macOS resource fork files
 		originalCopyFile := os.Getenv("COPYFILE_DISABLE")
@@ -389,132 +476,397 @@ func main() {
 
 		fmt.Println("Create template mode: Updating template from current project")
 
-		filesToCopy := make(map[string]bool)
-		err = filepath.Walk(projectDir, func(path string, info fs.FileInfo, err error) error {
+		templateVars.Preserves, err = processor.LoadPreserves(projectDir, templateDir)
+		if err != nil {
+			fmt.Println("Error loading protected phrases:", err)
+			os.Exit(1)
+		}
+		if extra := len(templateVars.Preserves) - len(processor.DefaultPreserves); extra > 0 {
+			fmt.Printf("Loaded %d protected phrases from %s\n", extra, processor.PreserveFile)
+		}
+
+		excluder, err := processor.NewExcluder(projectDir)
+		if err != nil {
+			fmt.Println("Error loading exclude rules:", err)
+			os.Exit(1)
+		}
+
+		// A dry run is not interactive, so ambiguous substitutions are left unchanged
+		var accept func(relPath string, hit processor.Hit) bool
+		if !args.IsDryRun {
+			accepted, err := reviewAmbiguousHits(projectDir, excluder, templateVars)
 			if err != nil {
-				return err
+				fmt.Println("Error scanning for ambiguous substitutions:", err)
+				os.Exit(1)
 			}
-
-			if yes, err := processor.IsExcluded(path, info); yes {
-				// fmt.Printf("Skipping directory or file: %s\n", path)
-				return err
+			accept = func(relPath string, hit processor.Hit) bool {
+				return accepted[hitKey{relPath, hit.Offset, hit.Token}]
 			}
+		}
 
-			relPath, _ := filepath.Rel(projectDir, path)
-			if relPath != "" {
-				filesToCopy[relPath] = true
+		// An overlay template only stores what differs from the template it extends
+		var parent []overlay.Layer
+		if parentRef := cmp.Or(args.Extends, templateManifest.Extends); parentRef != "" {
+			parentDir, err := templates.GetTemplateDir(parentRef)
+			if err == nil {
+				parent, err = templates.Layers(parentDir)
 			}
+			if err != nil {
+				fmt.Printf("Error resolving the template %s extends: %v\n", args.TemplateName, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Storing only the differences from template '%s'\n", parentRef)
+		}
 
-			return nil
-		})
-
+		report, err = generator.Create(projectDir, templateDir, templateVars, excluder, accept, parent, plan)
 		if err != nil {
-			fmt.Println("Error scanning current directory:", err)
+			fmt.Println("Error processing files:", err)
 			os.Exit(1)
 		}
+	}
 
-		fmt.Printf("Found %d files/directories in source\n", len(filesToCopy))
-
-		// Only clean template directory if it already exists
-		if _, err := os.Stat(templateDir); err == nil {
-			err = filepath.Walk(templateDir, func(path string, info fs.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
+	if plan != nil {
+		if args.IsJSON {
+			err = plan.WriteJSON(stdout)
+		} else {
+			plan.Print()
+		}
+		if err != nil {
+			fmt.Println("Error writing plan:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	report.Print()
 
-				relPath, _ := filepath.Rel(templateDir, path)
-				if relPath == "" || relPath == "." || relPath == ".wails-template.json" {
-					return nil
-				}
+	if !args.IsCreate && !args.IsRemove {
+		if err := bak.Close(); err != nil {
+			fmt.Println("Error saving backup:", err)
+			os.Exit(1)
+		}
+		if bak.ID != "" {
+			fmt.Printf("Backup %s holds the files this run replaced (undo with: create-local-app restore %s)\n", bak.ID, bak.ID)
+		}
 
-				if !filesToCopy[relPath] {
-					fmt.Printf("Removing file or folder from template: %s\n", relPath)
-					os.Remove(path)
-				}
-				return nil
-			})
-			if err != nil {
-				fmt.Println("Error cleaning template directory:", err)
+		// Hooks are skipped in auto mode, which is meant for quick regeneration
+		if !args.IsAuto {
+			if err := generator.RunHooks("postGenerate", templateManifest.Hooks.PostGenerate, projectDir, templateVars); err != nil {
+				fmt.Println("Error:", err)
 				os.Exit(1)
 			}
 		}
 
-		fmt.Println("Copying files to template with replacements...")
-		err = filepath.Walk(projectDir, func(path string, info fs.FileInfo, err error) error {
-			if err != nil {
-				return err
+		fmt.Println("✅ Project created at", projectDir)
+		fmt.Println("✅ Next steps ==> Run:")
+		fmt.Println()
+		fmt.Println(colors.BrightBlue, "  yarn lint && yarn test && yarn start", colors.Off)
+		fmt.Println()
+	} else {
+		fmt.Println("✅ Template updated from project at", projectDir)
+		if args.IsVerify {
+			if err := checkRoundTrip(templateDir, projectDir, templateVars); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
 			}
+		}
+	}
+}
 
-			if yes, err := processor.IsExcluded(path, info); yes {
-				return err
-			}
+// verifyTemplate checks that the named template, rendered with the values saved in the
+// current project's config, reproduces the project
+func verifyTemplate(templateName string) error {
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get project directory: %w", err)
+	}
 
-			relPath, err := filepath.Rel(projectDir, path)
-			if err != nil {
-				fmt.Printf("Error getting relative path for %s: %v\n", path, err)
-				return nil
-			}
+	appConfig, configPath, err := config.LoadProjectConfig()
+	if err != nil {
+		return err
+	}
+	if configPath != config.GetProjectConfigPath() {
+		return fmt.Errorf("no %s found - run template verify from the project the template was created from", filepath.Base(config.GetProjectConfigPath()))
+	}
 
-			targetPath := filepath.Join(templateDir, relPath)
+	templateDir, err := templates.GetTemplateDir(templateName)
+	if err != nil {
+		return err
+	}
+	templateManifest, err := templates.LoadManifest(templateDir)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
+	templateVars, err := generator.NewTemplateVars(appConfig, templateManifest)
+	if err != nil {
+		return err
+	}
 
-			targetDir := filepath.Dir(targetPath)
-			if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-				fmt.Printf("Error creating directory %s: %v\n", targetDir, err)
-				return nil
-			}
+	return checkRoundTrip(templateDir, projectDir, templateVars)
+}
 
-			// Skip reading and writing for directories
-			if info.IsDir() {
-				return nil
-			}
+// lintTemplate reports unknown placeholders and unused variables in the named template.
+// When run from a project with a saved config it also reports that project's values
+// left in the template as literals.
+func lintTemplate(templateName string) error {
+	templateDir, err := templates.GetTemplateDir(templateName)
+	if err != nil {
+		return err
+	}
+	templateManifest, err := templates.LoadManifest(templateDir)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
 
-			input, err := os.ReadFile(path)
-			if err != nil {
-				fmt.Printf("Error reading file %s: %v\n", path, err)
-				return nil
-			}
+	var templateVars *processor.TemplateVars
+	if _, err := os.Stat(config.GetProjectConfigPath()); err == nil {
+		appConfig, _, err := config.LoadProjectConfig()
+		if err != nil {
+			return err
+		}
+		if templateVars, err = generator.NewTemplateVars(appConfig, templateManifest); err != nil {
+			return err
+		}
+		if templateVars.Preserves, err = processor.LoadPreserves(".", templateDir); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("No %s in the current directory, skipping the check for leftover literal values\n", filepath.Base(config.GetProjectConfigPath()))
+	}
 
-			content := processor.ReverseTemplateVars(string(input), templateVars)
+	layers, err := templates.Layers(templateDir)
+	if err != nil {
+		return err
+	}
+	issues, err := lint.Check(layers, templateManifest, templateVars)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Printf("✅ No problems found in template '%s'\n", templateName)
+		return nil
+	}
 
-			if err := os.WriteFile(targetPath, []byte(content), info.Mode()); err != nil {
-				fmt.Printf("Error writing file %s: %v\n", targetPath, err)
-			}
+	for _, issue := range issues {
+		fmt.Println("   ", issue)
+	}
+	return fmt.Errorf("%d problems found in template '%s'", len(issues), templateName)
+}
 
-			return nil
-		})
+// installTemplate installs a contributed template from source, naming it after the
+// source unless name is given
+func installTemplate(source, name string) error {
+	fmt.Printf("Installing template from %s\n", source)
+	name, err := templates.Install(source, name)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("✅ Template '%s' installed - use it with: create-local-app --template %s\n", name, name)
+	return nil
+}
 
+// updateTemplate reinstalls a contributed template from the source it was installed from
+func updateTemplate(name string) error {
+	ref, updated, err := templates.Update(name)
 	if err != nil {
-		fmt.Println("Error processing files:", err)
-		os.Exit(1)
+		return err
+	}
+	src := templates.InstalledSource(ref)
+	if !updated {
+		fmt.Printf("Template '%s' is already up to date with %s\n", ref, src.Source)
+		return nil
 	}
+	fmt.Printf("✅ Template '%s' updated from %s (revision %s)\n", ref, src.Source, shortRevision(src.Revision))
+	return nil
+}
 
-	if !args.IsCreate && !args.IsRemove && !args.IsAuto {
-		fmt.Println("Running 'yarn install' in", projectDir)
-		cmd := exec.Command("yarn", "install")
-		cmd.Dir = projectDir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Warning: 'yarn install' failed: %v\n", err)
-		}
-		cmd = exec.Command("wails", "generate", "modules")
-		cmd.Dir = projectDir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Warning: 'wails generate modules' failed: %v\n", err)
+// exportTemplate writes a template to a .tar.gz at outPath, or <template-name>.tar.gz
+// (<template-name>-<version>.tar.gz for a pinned version) in the current directory
+func exportTemplate(templateName, outPath, version string) error {
+	if outPath == "" {
+		outPath = strings.Replace(templateName, "@", "-", 1) + ".tar.gz"
+	}
+	info, err := templates.Export(templateName, outPath, version)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Template '%s' exported to %s (%d files)\n", templateName, outPath, info.Files)
+	return nil
+}
+
+// shortRevision abbreviates a commit hash or content digest for display
+func shortRevision(revision string) string {
+	revision = strings.TrimPrefix(revision, "sha256:")
+	if len(revision) > 12 {
+		return revision[:12]
+	}
+	return revision
+}
+
+// updateProject merges the changes made to the current project's template since the
+// project was generated or last updated into the project
+func updateProject(version string) error {
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get project directory: %w", err)
+	}
+
+	appConfig, configPath, err := config.LoadProjectConfig()
+	if err != nil {
+		return err
+	}
+	if configPath != config.GetProjectConfigPath() {
+		return fmt.Errorf("no %s found - run update from a generated project", filepath.Base(config.GetProjectConfigPath()))
+	}
+
+	templateName := appConfig.Template
+	var templateDir string
+	if templateName != "" {
+		templateDir, err = templates.GetTemplateDir(templateName)
+	} else {
+		templateName = "default"
+		templateDir, err = templates.GetDefaultTemplateDir()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Updating project from template at:", templateDir)
+
+	templateManifest, err := templates.LoadManifest(templateDir)
+	if err != nil {
+		return fmt.Errorf("failed to load template manifest: %w", err)
+	}
+	templateVars, err := generator.NewTemplateVars(appConfig, templateManifest)
+	if err != nil {
+		return err
+	}
+
+	lock := generator.NewLock(version, templateName, templateDir, templateVars)
+	bak := backup.New(projectDir, "update")
+	changes, err := generator.Update(templateDir, projectDir, templateVars, appConfig, lock, bak)
+	if closeErr := bak.Close(); closeErr != nil {
+		return errors.Join(err, closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("✅ Project is already up to date with its template")
+		return nil
+	}
+	generator.PrintEntries(changes)
+	if bak.ID != "" {
+		fmt.Printf("Backup %s holds the files this update changed (undo with: create-local-app restore %s)\n", bak.ID, bak.ID)
+	}
+
+	conflicts := 0
+	for _, change := range changes {
+		if change.Action == generator.ActionConflict {
+			conflicts++
 		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d files need attention - resolve the conflict markers and %s files", conflicts, generator.RejectSuffix)
+	}
+	fmt.Println("✅ Project updated from template at", templateDir)
+	return nil
+}
 
-		os.Remove(filepath.Join(projectDir, ".wails-template.json"))
-		fmt.Println("✅ Project created at", projectDir)
-		fmt.Println("✅ Next steps ==> Run:")
-		fmt.Println()
-		fmt.Println(colors.BrightBlue, "  yarn lint && yarn test && yarn start", colors.Off)
+// restoreBackup lists the current project's backups, or rolls the project back to the
+// backup with the given id
+func restoreBackup(id string) error {
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get project directory: %w", err)
+	}
+
+	if id == "" {
+		backups, err := backup.List(projectDir)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups found for", projectDir)
+			return nil
+		}
+		fmt.Println("Backups of", projectDir)
+		for _, b := range backups {
+			fmt.Printf("  %-18s %s  %-26s %d files saved, %d files added\n", b.ID, b.Created.Format("2006-01-02 15:04:05"), b.Command, len(b.Saved), len(b.Added))
+		}
 		fmt.Println()
-	} else {
-		fmt.Println("✅ Template updated from project at", projectDir)
+		fmt.Println("Roll back with: create-local-app restore <backup-id>")
+		return nil
+	}
+
+	b, err := backup.Load(id)
+	if err != nil {
+		return err
+	}
+	if b.ProjectDir != projectDir {
+		return fmt.Errorf("backup %s was taken of %s - run restore from that folder", id, b.ProjectDir)
+	}
+
+	undo := backup.New(projectDir, "restore "+id)
+	err = b.Restore(undo)
+	if closeErr := undo.Close(); closeErr != nil {
+		err = errors.Join(err, closeErr)
+	}
+	if undo.ID != "" {
+		fmt.Printf("Backup %s holds the files as they were before this restore\n", undo.ID)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Restored %d files and removed %d files from backup %s\n", len(b.Saved), len(b.Added), id)
+	return nil
+}
+
+// generateProject renders the template at templateDir into projectDir, recording lock
+// with the files, and then, if cfg is not nil, saves it as the project's config, backed
+// up to bak first, and as the global defaults. The config is only written once every generated file is in place, so
+// a run that fails or is interrupted leaves the whole project as it was. changed reports
+// whether the files were put in place, even if saving the config then failed.
+func generateProject(ctx context.Context, templateDir, projectDir string, vars *processor.TemplateVars, appConfig, cfg *config.Config, lock *generator.Lock, bak *backup.Backup) (report *processor.FileReport, changed bool, err error) {
+	report, err = generator.Generate(ctx, templateDir, projectDir, vars, appConfig, lock, nil, bak)
+	if err != nil || cfg == nil {
+		return report, err == nil, err
+	}
+
+	// Save to project-local config to establish project-specific settings, so each
+	// project gets its own config file
+	if err := bak.Save(filepath.Base(config.GetProjectConfigPath())); err != nil {
+		return report, true, fmt.Errorf("failed to back up project config file: %w", err)
+	}
+	if err := config.SaveProjectConfig(cfg); err != nil {
+		return report, true, fmt.Errorf("failed to save project config file: %w", err)
+	}
+	// Also update global config for convenience as fallback defaults
+	if err := config.SaveGlobalConfig(cfg); err != nil {
+		return report, true, fmt.Errorf("failed to save global config file: %w", err)
+	}
+	return report, true, nil
+}
+
+// checkRoundTrip renders the template and reports every file that does not match the project
+func checkRoundTrip(templateDir, projectDir string, vars *processor.TemplateVars) error {
+	fmt.Println("Verifying that the template reproduces", projectDir)
+
+	excluder, err := processor.NewExcluder(projectDir)
+	if err != nil {
+		return fmt.Errorf("failed to load exclude rules: %w", err)
+	}
+	mismatches, err := generator.Verify(templateDir, projectDir, vars, excluder)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) == 0 {
+		fmt.Println("✅ Template round-trips cleanly")
+		return nil
 	}
+
+	for _, m := range mismatches {
+		fmt.Printf("    %s%s%s: %s\n", colors.Red, m.Path, colors.Off, m.Reason)
+	}
+	return fmt.Errorf("%d files do not round-trip", len(mismatches))
 }
 
 func checkLocalFolder(projectDir string, args *config.Args) {
@@ -542,10 +894,16 @@ func checkLocalFolder(projectDir string, args *config.Args) {
 			"README.md",
 			"LICENSE",
 			".create-local-app.json",
+			generator.SnapshotFile,
+			generator.LockFile,
 			".env",
 			"go.mod",
 			"appicon.png",
 		}
+		// A staging directory left behind by a run that was killed is not project work
+		if strings.HasPrefix(e.Name(), generator.StagePrefix) {
+			return true
+		}
 		for _, okFile := range okayFiles {
 			if e.Name() == okFile {
 				// logger.InfoBB("File", e.Name(), "found. Okay")
@@ -560,7 +918,7 @@ func checkLocalFolder(projectDir string, args *config.Args) {
 	if len(dirEntries) > 0 && !args.IsAuto {
 		if !args.IsForce {
 			fmt.Println("The current directory (" + projectDir + ") contains files.")
-			fmt.Println("Proceeding will overwrite existing files in an unrecoverable way.")
+			fmt.Println("Proceeding will overwrite existing files (they are backed up first - see restore).")
 			fmt.Println("Use --force flag to proceed without this check.")
 			for _, file := range dirEntries {
 				fmt.Println("    ", file)
@@ -569,3 +927,111 @@ func checkLocalFolder(projectDir string, args *config.Args) {
 		}
 	}
 }
+
+// warnModified lists the generated files the user has changed since the project's lock
+// file was written, since regenerating overwrites them
+func warnModified(projectDir string) {
+	lock, err := generator.ReadLock(projectDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Println("Warning:", err)
+		}
+		return
+	}
+	modified, err := lock.Modified(projectDir)
+	if err != nil {
+		fmt.Println("Warning: failed to check for modified files:", err)
+		return
+	}
+	if len(modified) > 0 {
+		fmt.Printf("Warning: %d files changed since the project was generated will be overwritten (use update to keep the changes):\n", len(modified))
+		for _, relPath := range modified {
+			fmt.Println("    ", relPath)
+		}
+	}
+}
+
+// hitKey identifies an ambiguous substitution by file, offset and placeholder
+type hitKey struct {
+	relPath string
+	offset  int
+	token   string
+}
+
+// reviewAmbiguousHits reports every project value found inside a larger word (for
+// example a project named "app" inside "mapping") and asks the user which of them
+// should be templatized. It returns the accepted substitutions.
+func reviewAmbiguousHits(projectDir string, excluder *processor.Excluder, vars *processor.TemplateVars) (map[hitKey]bool, error) {
+	type fileHit struct {
+		relPath string
+		hit     processor.Hit
+	}
+
+	var found []fileHit
+	err := filepath.Walk(projectDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if yes, err := excluder.IsExcluded(path, info); yes {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		input, err := os.ReadFile(path)
+		if err != nil || processor.IsBinary(path, input) {
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(projectDir, path)
+		for _, hit := range processor.FindAmbiguous(string(input), vars) {
+			found = append(found, fileHit{relPath, hit})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[hitKey]bool)
+	if len(found) == 0 {
+		return accepted, nil
+	}
+
+	fmt.Printf("Found %d ambiguous substitutions (a project value inside a larger word):\n", len(found))
+	for _, f := range found {
+		fmt.Printf("    %s:%d: %s%s%s -> %s in \"%s\"\n", f.relPath, f.hit.Line, colors.BrightBlue, f.hit.Value, colors.Off, f.hit.Token, f.hit.Context)
+	}
+
+	fmt.Print("Templatize these? [r]eview each, [a]ccept all, [N]o to leave all unchanged: ")
+	response, _ := stdin.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "a", "all":
+		for _, f := range found {
+			accepted[hitKey{f.relPath, f.hit.Offset, f.hit.Token}] = true
+		}
+	case "r", "review":
+		for i, f := range found {
+			fmt.Printf("%s:%d: %s -> %s in \"%s\"\n", f.relPath, f.hit.Line, f.hit.Value, f.hit.Token, f.hit.Context)
+			fmt.Print("Replace? [y]es, [N]o, [a]ccept remaining, [s]kip remaining: ")
+			answer, _ := stdin.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			if answer == "s" {
+				break
+			}
+			if answer == "a" {
+				for _, rest := range found[i:] {
+					accepted[hitKey{rest.relPath, rest.hit.Offset, rest.hit.Token}] = true
+				}
+				break
+			}
+			if answer == "y" || answer == "yes" {
+				accepted[hitKey{f.relPath, f.hit.Offset, f.hit.Token}] = true
+			}
+		}
+	}
+
+	fmt.Printf("Accepted %d of %d ambiguous substitutions\n", len(accepted), len(found))
+	return accepted, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/generator"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+)
+
+func TestGenerateProjectConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		t.Fatalf("GetUserConfigDir() unexpected error: %v", err)
+	}
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	templateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(templateDir, "README.md"), []byte("# {{PROJECT_NAME}}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	cfg := &config.Config{Organization: "Acme, Inc", ProjectName: "widget", Github: "github.com/acme/widget", Domain: "acme.io"}
+	vars, err := generator.NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+
+	t.Run("failed run leaves the config alone", func(t *testing.T) {
+		projectDir := t.TempDir()
+		t.Chdir(projectDir)
+		original := []byte(`{"ProjectName": "old"}` + "\n")
+		configPath := filepath.Join(projectDir, filepath.Base(config.GetProjectConfigPath()))
+		if err := os.WriteFile(configPath, original, 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, changed, err := generateProject(ctx, templateDir, projectDir, vars, cfg, cfg, nil, backup.New(projectDir, "generate"))
+		if err == nil || changed {
+			t.Fatalf("generateProject() = %v, %v, want an error with nothing changed", changed, err)
+		}
+		if data, _ := os.ReadFile(configPath); string(data) != string(original) {
+			t.Errorf("generateProject() rewrote the project config to %q", data)
+		}
+		if globalPath, _ := config.GetConfigPath(); fileExists(globalPath) {
+			t.Errorf("generateProject() wrote the global config %s", globalPath)
+		}
+	})
+
+	t.Run("successful run saves the config", func(t *testing.T) {
+		projectDir := t.TempDir()
+		t.Chdir(projectDir)
+		_, changed, err := generateProject(context.Background(), templateDir, projectDir, vars, cfg, cfg, generator.NewLock("test", "test", templateDir, vars), nil)
+		if err != nil || !changed {
+			t.Fatalf("generateProject() = %v, %v, want the project generated", changed, err)
+		}
+		saved, err := config.LoadConfig(filepath.Join(projectDir, filepath.Base(config.GetProjectConfigPath())))
+		if err != nil || saved.ProjectName != "widget" {
+			t.Errorf("project config = %+v, %v, want the run's values", saved, err)
+		}
+	})
+}
+
+// fileExists reports whether there is a file at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,284 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+)
+
+// infoFile describes a backup inside its directory
+const infoFile = "backup.json"
+
+// idFormat names a backup after the time it was taken
+const idFormat = "20060102-150405"
+
+// Backup holds copies of the project files one run overwrote or removed, and lists the
+// files the run created, so the project can be put back the way it was. A nil Backup
+// records nothing.
+type Backup struct {
+	ID         string    `json:"id"`
+	ProjectDir string    `json:"projectDir"`
+	Command    string    `json:"command"`
+	Created    time.Time `json:"created"`
+	Saved      []string  `json:"saved,omitempty"`
+	Added      []string  `json:"added,omitempty"`
+	dir        string
+	seen       map[string]bool
+}
+
+// Dir returns the directory holding all backups
+func Dir() (string, error) {
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "backups"), nil
+}
+
+// New starts a backup of the project at projectDir for the named command. Nothing is
+// written until the first file is saved.
+func New(projectDir, command string) *Backup {
+	return &Backup{
+		ProjectDir: projectDir,
+		Command:    command,
+		Created:    time.Now(),
+		seen:       make(map[string]bool),
+	}
+}
+
+// Save records the state of a project file before it is written or removed: an existing
+// file is copied into the backup, a missing one is noted as added by the run. Only the
+// first call for a path counts.
+func (b *Backup) Save(relPath string) error {
+	if b == nil || b.seen[relPath] {
+		return nil
+	}
+	b.seen[relPath] = true
+
+	if err := b.open(); err != nil {
+		return err
+	}
+
+	source := filepath.Join(b.ProjectDir, relPath)
+	info, err := os.Lstat(source)
+	if os.IsNotExist(err) {
+		b.Added = append(b.Added, filepath.ToSlash(relPath))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", relPath, err)
+	}
+	if info.IsDir() {
+		return nil
+	}
+	if err := copyFile(source, filepath.Join(b.dir, "files", relPath), info.Mode()); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", relPath, err)
+	}
+	b.Saved = append(b.Saved, filepath.ToSlash(relPath))
+	return nil
+}
+
+// open creates the backup's directory, choosing an ID not already in use
+func (b *Backup) open() error {
+	if b.dir != "" {
+		return nil
+	}
+	root, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %w", root, err)
+	}
+
+	base := b.Created.Format(idFormat)
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id += "-" + strconv.Itoa(n)
+		}
+		dir := filepath.Join(root, id)
+		err := os.Mkdir(dir, 0755)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create backup directory %s: %w", dir, err)
+		}
+		b.ID, b.dir = id, dir
+		return nil
+	}
+}
+
+// Close writes the backup's description. A backup that saved nothing leaves no trace.
+func (b *Backup) Close() error {
+	if b == nil || b.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup %s: %w", b.ID, err)
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, infoFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write backup %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// Discard deletes the backup, for a run that ended up changing nothing
+func (b *Backup) Discard() error {
+	if b == nil || b.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(b.dir)
+}
+
+// Load reads the backup with the given ID
+func Load(id string) (*Backup, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid backup id '%s'", id)
+	}
+	dir := filepath.Join(root, id)
+	data, err := os.ReadFile(filepath.Join(dir, infoFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup '%s' not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", id, err)
+	}
+	b := &Backup{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("failed to parse backup %s: %w", id, err)
+	}
+	b.ID, b.dir = id, dir
+	return b, nil
+}
+
+// List returns the backups taken of the project at projectDir, newest first
+func List(projectDir string) ([]*Backup, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory %s: %w", root, err)
+	}
+
+	var backups []*Backup
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		b, err := Load(entry.Name())
+		if err != nil {
+			// An unfinished backup (interrupted before Close) has no description
+			continue
+		}
+		if b.ProjectDir == projectDir {
+			backups = append(backups, b)
+		}
+	}
+	slices.SortFunc(backups, func(x, y *Backup) int {
+		if c := y.Created.Compare(x.Created); c != 0 {
+			return c
+		}
+		return strings.Compare(y.ID, x.ID)
+	})
+	return backups, nil
+}
+
+// Restore puts the backed-up files back into the project and removes the files the run
+// added. Each file it changes is first saved to undo, so the restore can itself be undone.
+func (b *Backup) Restore(undo *Backup) error {
+	for _, relPath := range b.Saved {
+		source := filepath.Join(b.dir, "files", filepath.FromSlash(relPath))
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("backup %s is missing %s: %w", b.ID, relPath, err)
+		}
+		target := filepath.Join(b.ProjectDir, filepath.FromSlash(relPath))
+		if sameFile(source, target) {
+			continue
+		}
+		if err := undo.Save(filepath.FromSlash(relPath)); err != nil {
+			return err
+		}
+		if err := copyFile(source, target, info.Mode()); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", relPath, err)
+		}
+	}
+
+	for _, relPath := range b.Added {
+		target := filepath.Join(b.ProjectDir, filepath.FromSlash(relPath))
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			continue
+		}
+		if err := undo.Save(filepath.FromSlash(relPath)); err != nil {
+			return err
+		}
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", relPath, err)
+		}
+		removeEmptyParents(filepath.Dir(target), b.ProjectDir)
+	}
+	return nil
+}
+
+// copyFile copies source to target, creating target's directory if needed
+func copyFile(source, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// sameFile reports whether two files can be read and have the same content
+func sameFile(a, b string) bool {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	dataB, err := os.ReadFile(b)
+	return err == nil && bytes.Equal(dataA, dataB)
+}
+
+// removeEmptyParents removes dir and its parents while they are empty, stopping at root
+func removeEmptyParents(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
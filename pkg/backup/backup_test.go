@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestBackup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	projectDir := t.TempDir()
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	// A run that changes nothing leaves no backup
+	empty := New(projectDir, "generate")
+	if err := empty.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	testutil.WriteFiles(t, projectDir, map[string]string{
+		"README.md":     "original readme\n",
+		"src/app.go":    "original app\n",
+		"untouched.txt": "untouched\n",
+	})
+
+	// Simulate a run that overwrites two files and adds one in a new folder
+	b := New(projectDir, "generate")
+	for _, rel := range []string{"README.md", filepath.Join("src", "app.go"), filepath.Join("new", "added.txt"), "README.md"} {
+		if err := b.Save(rel); err != nil {
+			t.Fatalf("Save(%s) unexpected error: %v", rel, err)
+		}
+	}
+	testutil.WriteFiles(t, projectDir, map[string]string{
+		"README.md":     "generated readme\n",
+		"src/app.go":    "generated app\n",
+		"new/added.txt": "added\n",
+	})
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	if want := []string{"README.md", "src/app.go"}; !slices.Equal(b.Saved, want) {
+		t.Errorf("Saved = %v, want %v", b.Saved, want)
+	}
+	if want := []string{"new/added.txt"}; !slices.Equal(b.Added, want) {
+		t.Errorf("Added = %v, want %v", b.Added, want)
+	}
+
+	backups, err := List(projectDir)
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(backups) != 1 || backups[0].ID != b.ID || backups[0].Command != "generate" {
+		t.Fatalf("List() = %v, want only backup %s", backups, b.ID)
+	}
+	if others, _ := List(t.TempDir()); len(others) != 0 {
+		t.Errorf("List() of another project = %v, want none", others)
+	}
+
+	loaded, err := Load(b.ID)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	undo := New(projectDir, "restore "+b.ID)
+	if err := loaded.Restore(undo); err != nil {
+		t.Fatalf("Restore() unexpected error: %v", err)
+	}
+	if err := undo.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	for rel, want := range map[string]string{
+		"README.md":     "original readme\n",
+		"src/app.go":    "original app\n",
+		"untouched.txt": "untouched\n",
+		"new/added.txt": "<missing>",
+	} {
+		if got := read(rel); got != want {
+			t.Errorf("after Restore() %s = %q, want %q", rel, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "new")); !os.IsNotExist(err) {
+		t.Errorf("Restore() left the empty folder new behind")
+	}
+
+	// The restore can itself be undone
+	if undo.ID == b.ID || !slices.Equal(undo.Saved, []string{"README.md", "src/app.go", "new/added.txt"}) {
+		t.Errorf("undo backup %s saved %v, want a new backup of all three files", undo.ID, undo.Saved)
+	}
+
+	if _, err := Load("../escape"); err == nil {
+		t.Errorf("Load() with a path in the id expected an error")
+	}
+	if _, err := Load("19990101-000000"); err == nil {
+		t.Errorf("Load() of a missing backup expected an error")
+	}
+}
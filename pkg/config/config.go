@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
 )
 
 // ViewConfigEntry represents configuration for a single view in .create-local-app.json
@@ -17,27 +22,104 @@ type ViewConfigEntry struct {
 
 // Config represents the application configuration
 type Config struct {
-	Organization  string                     `json:"Organization"`
-	ProjectName   string                     `json:"ProjectName"`
-	Github        string                     `json:"Github"`
-	Domain        string                     `json:"Domain"`
-	Template      string                     `json:"Template"`
-	PreserveFiles []string                   `json:"PreserveFiles,omitempty"`
-	ViewConfig    map[string]ViewConfigEntry `json:"ViewConfig,omitempty"`
+	Organization   string                     `json:"Organization"`
+	PublisherName  string                     `json:"PublisherName"`
+	PublisherEmail string                     `json:"PublisherEmail"`
+	ProjectName    string                     `json:"ProjectName"`
+	Github         string                     `json:"Github"`
+	Domain         string                     `json:"Domain"`
+	Template       string                     `json:"Template"`
+	Features       []string                   `json:"Features,omitempty"`
+	Variables      map[string]string          `json:"Variables,omitempty"`
+	PreserveFiles  []string                   `json:"PreserveFiles,omitempty"`
+	ViewConfig     map[string]ViewConfigEntry `json:"ViewConfig,omitempty"`
+}
+
+// GetValue returns the saved value of a template variable. The built-in variables map to
+// their named fields; any other variable declared by a template manifest lives in Variables.
+func (c *Config) GetValue(name string) string {
+	switch name {
+	case "ORGANIZATION":
+		return c.Organization
+	case "PUBLISHER_NAME":
+		return c.PublisherName
+	case "PUBLISHER_EMAIL":
+		return c.PublisherEmail
+	case "PROJECT_NAME":
+		return c.ProjectName
+	case "GITHUB":
+		return c.Github
+	case "DOMAIN":
+		return c.Domain
+	default:
+		return c.Variables[name]
+	}
+}
+
+// SetValue stores the value of a template variable (see GetValue)
+func (c *Config) SetValue(name, value string) {
+	switch name {
+	case "ORGANIZATION":
+		c.Organization = value
+	case "PUBLISHER_NAME":
+		c.PublisherName = value
+	case "PUBLISHER_EMAIL":
+		c.PublisherEmail = value
+	case "PROJECT_NAME":
+		c.ProjectName = value
+	case "GITHUB":
+		c.Github = value
+	case "DOMAIN":
+		c.Domain = value
+	default:
+		if c.Variables == nil {
+			c.Variables = make(map[string]string)
+		}
+		c.Variables[name] = value
+	}
+}
+
+// GitPublisher returns the user's name and email from their git configuration, or empty
+// strings if git is not installed or they are not set
+func GitPublisher() (name, email string) {
+	read := func(key string) string {
+		out, err := exec.Command("git", "config", "--get", key).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return read("user.name"), read("user.email")
 }
 
 // Args represents parsed command line arguments
 type Args struct {
-	IsAuto       bool
-	IsCreate     bool
-	IsRemove     bool
-	IsForce      bool
-	IsList       bool
-	IsCustomize  bool
-	TemplateName string
-	UseTemplate  string
+	IsAuto          bool
+	IsCreate        bool
+	IsRemove        bool
+	IsForce         bool
+	IsList          bool
+	IsCustomize     bool
+	IsVerify        bool
+	IsDryRun        bool
+	IsJSON          bool
+	IsUpdate        bool
+	IsRestore       bool
+	BackupID        string
+	TemplateName    string
+	Extends         string
+	TemplateCommand string
+	TemplateSource  string
+	OutputFile      string
+	UseTemplate     string
+	Features        []string
+	HasFeatures     bool
 }
 
+// templateCommands are the verbs accepted by "template <command> <template-name>", apart
+// from install, which takes a source instead
+var templateCommands = []string{"verify", "lint", "install", "update", "export"}
+
 // ParseArgs parses command line arguments and returns Args struct or handles special commands
 func ParseArgs(version, buildTime string) (*Args, error) {
 	args := &Args{}
@@ -58,23 +140,93 @@ func ParseArgs(version, buildTime string) (*Args, error) {
 					return nil, fmt.Errorf("--create requires a template name parameter")
 				}
 				templateName := os.Args[i+1]
-				if !isValidTemplateName(templateName) {
-					return nil, fmt.Errorf("invalid template name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", templateName)
+				if err := checkTemplateRef(templateName); err != nil {
+					return nil, err
 				}
 				args.IsCreate = true
 				args.TemplateName = templateName
 				i += 2 // Skip the template name argument
+			case "--extends":
+				if i+1 >= len(os.Args) {
+					return nil, fmt.Errorf("--extends requires a template name parameter")
+				}
+				parentName := os.Args[i+1]
+				if err := checkTemplateRef(parentName); err != nil {
+					return nil, err
+				}
+				args.Extends = parentName
+				i += 2 // Skip the template name argument
 			case "--remove":
 				if i+1 >= len(os.Args) {
 					return nil, fmt.Errorf("--remove requires a template name parameter")
 				}
 				templateName := os.Args[i+1]
-				if !isValidTemplateName(templateName) {
-					return nil, fmt.Errorf("invalid template name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", templateName)
+				if err := checkTemplateRef(templateName); err != nil {
+					return nil, err
 				}
 				args.IsRemove = true
 				args.TemplateName = templateName
 				i += 2 // Skip the template name argument
+			case "template":
+				if i+1 >= len(os.Args) || !slices.Contains(templateCommands, os.Args[i+1]) {
+					return nil, fmt.Errorf("template requires a command (valid commands: %s)", strings.Join(templateCommands, ", "))
+				}
+				command := os.Args[i+1]
+				if command == "install" {
+					if i+2 >= len(os.Args) || strings.HasPrefix(os.Args[i+2], "-") {
+						return nil, fmt.Errorf("template install requires a source (a .tar.gz or .zip file, a directory or a git repository)")
+					}
+					args.TemplateCommand = command
+					args.TemplateSource = os.Args[i+2]
+					i += 3 // Skip the command and source arguments
+					if i < len(os.Args) && os.Args[i] == "--name" {
+						if i+1 >= len(os.Args) {
+							return nil, fmt.Errorf("--name requires a template name parameter")
+						}
+						if !IsValidTemplateName(os.Args[i+1]) {
+							return nil, fmt.Errorf("invalid template name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", os.Args[i+1])
+						}
+						args.TemplateName = os.Args[i+1]
+						i += 2 // Skip the name argument
+					}
+					continue
+				}
+				if i+2 >= len(os.Args) {
+					return nil, fmt.Errorf("template %s requires a template name parameter", command)
+				}
+				templateName := os.Args[i+2]
+				if err := checkTemplateRef(templateName); err != nil {
+					return nil, err
+				}
+				args.TemplateCommand = command
+				args.TemplateName = templateName
+				i += 3 // Skip the command and template name arguments
+				if command == "export" && i < len(os.Args) && (os.Args[i] == "-o" || os.Args[i] == "--output") {
+					if i+1 >= len(os.Args) {
+						return nil, fmt.Errorf("%s requires a file parameter", os.Args[i])
+					}
+					args.OutputFile = os.Args[i+1]
+					i += 2 // Skip the output file argument
+				}
+			case "update":
+				args.IsUpdate = true
+				i++
+			case "restore":
+				args.IsRestore = true
+				i++
+				if i < len(os.Args) && !strings.HasPrefix(os.Args[i], "-") {
+					args.BackupID = os.Args[i]
+					i++ // Skip the backup id argument
+				}
+			case "--verify":
+				args.IsVerify = true
+				i++
+			case "--dry-run":
+				args.IsDryRun = true
+				i++
+			case "--json":
+				args.IsJSON = true
+				i++
 			case "--auto":
 				args.IsAuto = true
 				i++
@@ -92,13 +244,24 @@ func ParseArgs(version, buildTime string) (*Args, error) {
 					return nil, fmt.Errorf("--template requires a template name parameter")
 				}
 				templateName := os.Args[i+1]
-				if !isValidTemplateName(templateName) {
-					return nil, fmt.Errorf("invalid template name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", templateName)
+				if err := checkTemplateRef(templateName); err != nil {
+					return nil, err
 				}
 				args.UseTemplate = templateName
 				i += 2 // Skip the template name argument
+			case "--features":
+				if i+1 >= len(os.Args) {
+					return nil, fmt.Errorf("--features requires a comma-separated list of features")
+				}
+				features, err := parseFeatures(os.Args[i+1])
+				if err != nil {
+					return nil, err
+				}
+				args.Features = features
+				args.HasFeatures = true
+				i += 2 // Skip the feature list argument
 			default:
-				return nil, fmt.Errorf("unknown argument: %s (valid options: --create <template-name>, --remove <template-name>, --template <template-name>, --auto, --force, --list, --customize, --version, --help)", os.Args[i])
+				return nil, fmt.Errorf("unknown argument: %s (valid options: --create <template-name>, --extends <template-name>, --remove <template-name>, --template <template-name>, --features <list>, --verify, --dry-run, --json, --auto, --force, --list, --customize, --version, --help, update, restore [backup-id], template <command> <template-name>)", os.Args[i])
 			}
 		}
 	}
@@ -107,9 +270,6 @@ func ParseArgs(version, buildTime string) (*Args, error) {
 	if args.IsCreate && args.IsAuto {
 		return nil, fmt.Errorf("--create and --auto flags are incompatible (auto mode is for project creation, not template creation)")
 	}
-	if args.IsCreate && args.IsForce {
-		return nil, fmt.Errorf("--create and --force flags are incompatible (force mode is for project creation, not template creation)")
-	}
 	if args.IsRemove && args.IsAuto {
 		return nil, fmt.Errorf("--remove and --auto flags are incompatible (auto mode is for project creation, not template removal)")
 	}
@@ -131,6 +291,33 @@ func ParseArgs(version, buildTime string) (*Args, error) {
 	if args.IsCustomize && args.IsRemove {
 		return nil, fmt.Errorf("--customize and --remove flags are incompatible (customize mode is for existing projects)")
 	}
+	if args.HasFeatures && (args.IsCreate || args.IsRemove) {
+		return nil, fmt.Errorf("--features is only valid when generating a project")
+	}
+	if args.IsVerify && !args.IsCreate {
+		return nil, fmt.Errorf("--verify is only valid with --create")
+	}
+	if args.Extends != "" && !args.IsCreate {
+		return nil, fmt.Errorf("--extends can only be used with --create")
+	}
+	if args.IsJSON && !args.IsDryRun {
+		return nil, fmt.Errorf("--json is only valid with --dry-run")
+	}
+	if args.IsDryRun && (args.IsRemove || args.IsVerify || args.IsList || args.IsCustomize) {
+		return nil, fmt.Errorf("--dry-run is only valid when generating a project or creating a template")
+	}
+	if args.TemplateCommand != "" && (args.IsCreate || args.IsRemove || args.IsAuto || args.IsForce ||
+		args.IsList || args.IsCustomize || args.UseTemplate != "" || args.HasFeatures || args.IsDryRun) {
+		return nil, fmt.Errorf("template %s cannot be combined with other options", args.TemplateCommand)
+	}
+	if args.IsUpdate && (args.IsCreate || args.IsRemove || args.IsAuto || args.IsForce || args.IsList ||
+		args.IsCustomize || args.IsVerify || args.IsDryRun || args.UseTemplate != "" || args.HasFeatures || args.TemplateCommand != "") {
+		return nil, fmt.Errorf("update cannot be combined with other options")
+	}
+	if args.IsRestore && (args.IsUpdate || args.IsCreate || args.IsRemove || args.IsAuto || args.IsForce || args.IsList ||
+		args.IsCustomize || args.IsVerify || args.IsDryRun || args.UseTemplate != "" || args.HasFeatures || args.TemplateCommand != "") {
+		return nil, fmt.Errorf("restore cannot be combined with other options")
+	}
 	if args.IsCustomize && args.IsList {
 		return nil, fmt.Errorf("--customize and --list flags are incompatible")
 	}
@@ -144,34 +331,68 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("USAGE:")
 	fmt.Println("  create-local-app [options]")
+	fmt.Println("  create-local-app update")
+	fmt.Println("  create-local-app restore [backup-id]")
+	fmt.Println("  create-local-app template <command> <template-name>")
+	fmt.Println("  create-local-app template install <source> [--name <template-name>]")
+	fmt.Println("  create-local-app template export <template-name> [-o <file>]")
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  --auto                           Use saved configuration without prompts")
 	fmt.Println("  --list                           List available templates")
-	fmt.Println("  --create <template-name>         Create a template from the current directory")
+	fmt.Println("  --create <template-name>         Create a template from the current directory (name@version adds a new version)")
+	fmt.Println("  --extends <template-name>        With --create, store only what differs from that template")
 	fmt.Println("  --remove <template-name>         Remove a contributed template")
-	fmt.Println("  --template <template-name>       Optionally, use a specific template")
+	fmt.Println("  --template <template-name>       Optionally, use a specific template (name@version pins a version)")
+	fmt.Println("  --features <list>                Comma-separated optional template features (e.g. dalle,ai)")
+	fmt.Println("  --verify                         With --create, check that the new template reproduces the project")
+	fmt.Println("  --dry-run                        Show what would be written, overwritten, skipped or removed without writing")
+	fmt.Println("  --json                           With --dry-run, print the plan as JSON")
 	fmt.Println("  --customize                      Interactively customize enabled/disabled views")
-	fmt.Println("  --force                          Force operation without confirmation (overwrite existing files)")
+	fmt.Println("  --force                          Force operation without confirmation (overwrite existing files or template versions)")
 	fmt.Println("  --version                        Show version information")
 	fmt.Println("  --help                           Show this help message")
 	fmt.Println()
+	fmt.Println("PROJECT COMMANDS:")
+	fmt.Println("  update                           Merge changes to the project's template into the project, keeping local edits")
+	fmt.Println("  restore [backup-id]              List this project's backups, or roll the project back to one of them")
+	fmt.Println()
+	fmt.Println("TEMPLATE COMMANDS:")
+	fmt.Println("  verify <template-name>           Render a template with this project's saved values and diff it against the project")
+	fmt.Println("  lint <template-name>             Report unknown placeholders, unused variables and leftover literal values")
+	fmt.Println("  install <source> [--name <name>] Install a contributed template from a .tar.gz or .zip file, a directory or a git repository")
+	fmt.Println("  update <template-name>           Reinstall an installed template from its source if the source has changed")
+	fmt.Println("  export <template-name> [-o file] Write a template to a reproducible .tar.gz (default <template-name>.tar.gz)")
+	fmt.Println()
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  create-local-app                           # Interactive mode - prompts for project details")
 	fmt.Println("  create-local-app --auto                    # Use previously saved configuration")
 	fmt.Println("  create-local-app --list                    # List available templates")
 	fmt.Println("  create-local-app --create my-template      # Create template from current directory")
+	fmt.Println("  create-local-app --create my-template --verify  # Create template and check it round-trips")
+	fmt.Println("  create-local-app --dry-run                 # Preview which files generation would change")
+	fmt.Println("  create-local-app update                    # Bring this project up to date with its template")
+	fmt.Println("  create-local-app restore 20250101-120000   # Undo the generation or update that made that backup")
+	fmt.Println("  create-local-app template verify my-template    # Check an existing template against this project")
+	fmt.Println("  create-local-app template lint my-template      # Check a template for placeholder mistakes")
+	fmt.Println("  create-local-app template install https://github.com/acme/my-template.git  # Install a shared template")
+	fmt.Println("  create-local-app template update my-template    # Pull the latest version of an installed template")
+	fmt.Println("  create-local-app template export my-template -o my-template-1.0.tar.gz  # Package a template to share")
 	fmt.Println("  create-local-app --remove my-template      # Remove contributed template")
 	fmt.Println("  create-local-app --template my-template    # Use a specific template")
+	fmt.Println("  create-local-app --template my-template@1.2  # Use version 1.2 rather than the newest")
+	fmt.Println("  create-local-app --create my-template@1.3  # Add version 1.3 alongside the earlier ones")
+	fmt.Println("  create-local-app --create my-template --extends default  # Keep only the changes to default")
+	fmt.Println("  create-local-app --features dalle          # Include the template's optional dalle sections")
 	fmt.Println("  create-local-app --customize               # Customize enabled/disabled views interactively")
 	fmt.Println("  create-local-app --force                   # Overwrite existing files without confirmation")
 	fmt.Println()
 	fmt.Println("For more information, visit: https://github.com/TrueBlocks/create-local-app")
 }
 
-// isValidTemplateName validates that a template name starts with alphanumeric
+// IsValidTemplateName validates that a template name starts with alphanumeric
 // and contains only alphanumeric characters and dashes
-func isValidTemplateName(name string) bool {
+func IsValidTemplateName(name string) bool {
 	if name == "" {
 		return false
 	}
@@ -181,6 +402,44 @@ func isValidTemplateName(name string) bool {
 	return matched
 }
 
+// SplitTemplateRef splits a template reference such as my-template@1.2 into the
+// template name and the version it pins, which is empty if it pins none
+func SplitTemplateRef(ref string) (name, version string) {
+	name, version, _ = strings.Cut(ref, "@")
+	return name, version
+}
+
+// checkTemplateRef validates a template name, optionally followed by @ and a version
+func checkTemplateRef(ref string) error {
+	name, version := SplitTemplateRef(ref)
+	if !IsValidTemplateName(name) {
+		return fmt.Errorf("invalid template name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", ref)
+	}
+	if strings.Contains(ref, "@") && !manifest.ValidVersion(version) {
+		return fmt.Errorf("invalid template version '%s': must start with alphanumeric and contain only alphanumeric characters, dots and dashes", ref)
+	}
+	return nil
+}
+
+// parseFeatures splits a comma-separated feature list, validating each name. An empty
+// list clears any previously saved features.
+func parseFeatures(list string) ([]string, error) {
+	features := []string{}
+	for _, feature := range strings.Split(list, ",") {
+		feature = strings.TrimSpace(feature)
+		if feature == "" {
+			continue
+		}
+		if !IsValidTemplateName(feature) {
+			return nil, fmt.Errorf("invalid feature name '%s': must start with alphanumeric and contain only alphanumeric characters and dashes", feature)
+		}
+		if !slices.Contains(features, feature) {
+			features = append(features, feature)
+		}
+	}
+	return features, nil
+}
+
 // LoadConfig loads configuration from file
 func LoadConfig(configPath string) (*Config, error) {
 	config := &Config{}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -57,15 +58,15 @@ func TestParseArgs(t *testing.T) {
 		},
 		{
 			name:    "create template mode with invalid template name - special chars",
-			args:    []string{"program", "--create", "invalid@name"},
+			args:    []string{"program", "--create", "invalid#name"},
 			wantErr: true,
-			errMsg:  "invalid template name 'invalid@name': must start with alphanumeric and contain only alphanumeric characters and dashes",
+			errMsg:  "invalid template name 'invalid#name': must start with alphanumeric and contain only alphanumeric characters and dashes",
 		},
 		{
 			name:    "unknown argument",
 			args:    []string{"program", "--unknown"},
 			wantErr: true,
-			errMsg:  "unknown argument: --unknown (valid options: --create <template-name>, --remove <template-name>, --template <template-name>, --auto, --force, --list, --version, --help)",
+			errMsg:  "unknown argument: --unknown (valid options: --create <template-name>, --extends <template-name>, --remove <template-name>, --template <template-name>, --features <list>, --verify, --dry-run, --json, --auto, --force, --list, --customize, --version, --help, update, restore [backup-id], template <command> <template-name>)",
 		},
 		{
 			name:     "force mode",
@@ -80,10 +81,189 @@ func TestParseArgs(t *testing.T) {
 			wantErr:  false,
 		},
 		{
-			name:    "create template and force combined - incompatible",
-			args:    []string{"program", "--create", "my-template", "--force"},
+			name:     "features list",
+			args:     []string{"program", "--features", "dalle, ai,dalle"},
+			wantArgs: &Args{Features: []string{"dalle", "ai"}, HasFeatures: true},
+			wantErr:  false,
+		},
+		{
+			name:     "empty features list clears features",
+			args:     []string{"program", "--features", ""},
+			wantArgs: &Args{Features: []string{}, HasFeatures: true},
+			wantErr:  false,
+		},
+		{
+			name:    "features with invalid name",
+			args:    []string{"program", "--features", "dalle,bad name"},
+			wantErr: true,
+			errMsg:  "invalid feature name 'bad name': must start with alphanumeric and contain only alphanumeric characters and dashes",
+		},
+		{
+			name:    "features with create - incompatible",
+			args:    []string{"program", "--create", "my-template", "--features", "dalle"},
+			wantErr: true,
+			errMsg:  "--features is only valid when generating a project",
+		},
+		{
+			name:     "create template with verify",
+			args:     []string{"program", "--create", "my-template", "--verify"},
+			wantArgs: &Args{IsCreate: true, IsVerify: true, TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:    "verify without create",
+			args:    []string{"program", "--verify"},
+			wantErr: true,
+			errMsg:  "--verify is only valid with --create",
+		},
+		{
+			name:     "create overlay template",
+			args:     []string{"program", "--create", "my-template", "--extends", "default@1.0"},
+			wantArgs: &Args{IsCreate: true, TemplateName: "my-template", Extends: "default@1.0"},
+			wantErr:  false,
+		},
+		{
+			name:    "extends without create",
+			args:    []string{"program", "--extends", "default"},
+			wantErr: true,
+			errMsg:  "--extends can only be used with --create",
+		},
+		{
+			name:     "dry run with json",
+			args:     []string{"program", "--dry-run", "--json"},
+			wantArgs: &Args{IsDryRun: true, IsJSON: true},
+			wantErr:  false,
+		},
+		{
+			name:     "dry run of create template",
+			args:     []string{"program", "--create", "my-template", "--dry-run"},
+			wantArgs: &Args{IsCreate: true, IsDryRun: true, TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:    "json without dry run",
+			args:    []string{"program", "--json"},
+			wantErr: true,
+			errMsg:  "--json is only valid with --dry-run",
+		},
+		{
+			name:    "dry run with remove - incompatible",
+			args:    []string{"program", "--remove", "my-template", "--dry-run"},
 			wantErr: true,
-			errMsg:  "--create and --force flags are incompatible (force mode is for project creation, not template creation)",
+			errMsg:  "--dry-run is only valid when generating a project or creating a template",
+		},
+		{
+			name:     "update command",
+			args:     []string{"program", "update"},
+			wantArgs: &Args{IsUpdate: true},
+			wantErr:  false,
+		},
+		{
+			name:    "update with other options",
+			args:    []string{"program", "update", "--force"},
+			wantErr: true,
+			errMsg:  "update cannot be combined with other options",
+		},
+		{
+			name:     "restore lists backups",
+			args:     []string{"program", "restore"},
+			wantArgs: &Args{IsRestore: true},
+			wantErr:  false,
+		},
+		{
+			name:     "restore a backup",
+			args:     []string{"program", "restore", "20250101-120000"},
+			wantArgs: &Args{IsRestore: true, BackupID: "20250101-120000"},
+			wantErr:  false,
+		},
+		{
+			name:    "restore with other options",
+			args:    []string{"program", "restore", "--auto"},
+			wantErr: true,
+			errMsg:  "restore cannot be combined with other options",
+		},
+		{
+			name:     "template verify command",
+			args:     []string{"program", "template", "verify", "my-template"},
+			wantArgs: &Args{TemplateCommand: "verify", TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:     "template lint command",
+			args:     []string{"program", "template", "lint", "my-template"},
+			wantArgs: &Args{TemplateCommand: "lint", TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:    "template with unknown command",
+			args:    []string{"program", "template", "frobnicate", "my-template"},
+			wantErr: true,
+			errMsg:  "template requires a command (valid commands: verify, lint, install, update, export)",
+		},
+		{
+			name:    "template command missing template name",
+			args:    []string{"program", "template", "verify"},
+			wantErr: true,
+			errMsg:  "template verify requires a template name parameter",
+		},
+		{
+			name:     "template install command",
+			args:     []string{"program", "template", "install", "file:///shared/my-template.git"},
+			wantArgs: &Args{TemplateCommand: "install", TemplateSource: "file:///shared/my-template.git"},
+			wantErr:  false,
+		},
+		{
+			name:     "template install command with a name",
+			args:     []string{"program", "template", "install", "../tpl.tar.gz", "--name", "my-template"},
+			wantArgs: &Args{TemplateCommand: "install", TemplateSource: "../tpl.tar.gz", TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:    "template install command missing source",
+			args:    []string{"program", "template", "install", "--name", "my-template"},
+			wantErr: true,
+			errMsg:  "template install requires a source (a .tar.gz or .zip file, a directory or a git repository)",
+		},
+		{
+			name:    "template install command with invalid name",
+			args:    []string{"program", "template", "install", "tpl.zip", "--name", "my template"},
+			wantErr: true,
+			errMsg:  "invalid template name 'my template': must start with alphanumeric and contain only alphanumeric characters and dashes",
+		},
+		{
+			name:     "template update command",
+			args:     []string{"program", "template", "update", "my-template"},
+			wantArgs: &Args{TemplateCommand: "update", TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:     "template export command",
+			args:     []string{"program", "template", "export", "my-template"},
+			wantArgs: &Args{TemplateCommand: "export", TemplateName: "my-template"},
+			wantErr:  false,
+		},
+		{
+			name:     "template export command with output file",
+			args:     []string{"program", "template", "export", "my-template", "-o", "dist/my-template.tar.gz"},
+			wantArgs: &Args{TemplateCommand: "export", TemplateName: "my-template", OutputFile: "dist/my-template.tar.gz"},
+			wantErr:  false,
+		},
+		{
+			name:    "template export command missing output file",
+			args:    []string{"program", "template", "export", "my-template", "--output"},
+			wantErr: true,
+			errMsg:  "--output requires a file parameter",
+		},
+		{
+			name:    "template command with other options",
+			args:    []string{"program", "template", "verify", "my-template", "--auto"},
+			wantErr: true,
+			errMsg:  "template verify cannot be combined with other options",
+		},
+		{
+			name:     "create template and force combined",
+			args:     []string{"program", "--create", "my-template@2.0", "--force"},
+			wantArgs: &Args{IsAuto: false, IsCreate: true, IsForce: true, TemplateName: "my-template@2.0"},
 		},
 		{
 			name:    "create template and auto combined - incompatible",
@@ -118,7 +298,19 @@ func TestParseArgs(t *testing.T) {
 					if args.IsAuto != tt.wantArgs.IsAuto ||
 						args.IsCreate != tt.wantArgs.IsCreate ||
 						args.IsForce != tt.wantArgs.IsForce ||
-						args.TemplateName != tt.wantArgs.TemplateName {
+						args.IsVerify != tt.wantArgs.IsVerify ||
+						args.IsDryRun != tt.wantArgs.IsDryRun ||
+						args.IsJSON != tt.wantArgs.IsJSON ||
+						args.IsUpdate != tt.wantArgs.IsUpdate ||
+						args.IsRestore != tt.wantArgs.IsRestore ||
+						args.BackupID != tt.wantArgs.BackupID ||
+						args.TemplateCommand != tt.wantArgs.TemplateCommand ||
+						args.TemplateSource != tt.wantArgs.TemplateSource ||
+						args.OutputFile != tt.wantArgs.OutputFile ||
+						args.TemplateName != tt.wantArgs.TemplateName ||
+						args.Extends != tt.wantArgs.Extends ||
+						args.HasFeatures != tt.wantArgs.HasFeatures ||
+						!slices.Equal(args.Features, tt.wantArgs.Features) {
 						t.Errorf("ParseArgs() = %+v, want %+v", args, tt.wantArgs)
 					}
 				}
@@ -145,15 +337,19 @@ func TestLoadConfig(t *testing.T) {
 			name: "valid config file",
 			configData: `{
   "Organization": "TrueBlocks, LLC",
+  "PublisherName": "Jane Doe",
+  "PublisherEmail": "jane@trueblocks.io",
   "ProjectName": "my-app",
   "Github": "https://github.com/TrueBlocks/my-app",
   "Domain": "trueblocks.io"
 }`,
 			wantConfig: &Config{
-				Organization: "TrueBlocks, LLC",
-				ProjectName:  "my-app",
-				Github:       "https://github.com/TrueBlocks/my-app",
-				Domain:       "trueblocks.io",
+				Organization:   "TrueBlocks, LLC",
+				PublisherName:  "Jane Doe",
+				PublisherEmail: "jane@trueblocks.io",
+				ProjectName:    "my-app",
+				Github:         "https://github.com/TrueBlocks/my-app",
+				Domain:         "trueblocks.io",
 			},
 			wantErr: false,
 		},
@@ -204,6 +400,9 @@ func TestLoadConfig(t *testing.T) {
 			if config.Organization != tt.wantConfig.Organization {
 				t.Errorf("LoadConfig() Organization = %v, want %v", config.Organization, tt.wantConfig.Organization)
 			}
+			if config.PublisherName != tt.wantConfig.PublisherName || config.PublisherEmail != tt.wantConfig.PublisherEmail {
+				t.Errorf("LoadConfig() Publisher = %v <%v>, want %v <%v>", config.PublisherName, config.PublisherEmail, tt.wantConfig.PublisherName, tt.wantConfig.PublisherEmail)
+			}
 			if config.ProjectName != tt.wantConfig.ProjectName {
 				t.Errorf("LoadConfig() ProjectName = %v, want %v", config.ProjectName, tt.wantConfig.ProjectName)
 			}
@@ -245,10 +444,12 @@ func TestSaveConfig(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	config := &Config{
-		Organization: "Test Organization",
-		ProjectName:  "test-project",
-		Github:       "https://github.com/test/test-project",
-		Domain:       "test.io",
+		Organization:   "Test Organization",
+		PublisherName:  "Test Publisher",
+		PublisherEmail: "publisher@test.io",
+		ProjectName:    "test-project",
+		Github:         "https://github.com/test/test-project",
+		Domain:         "test.io",
 	}
 
 	configPath := filepath.Join(tempDir, "test-save-config.json")
@@ -276,6 +477,9 @@ func TestSaveConfig(t *testing.T) {
 	if loadedConfig.Organization != config.Organization {
 		t.Errorf("Saved/loaded Organization mismatch: got %v, want %v", loadedConfig.Organization, config.Organization)
 	}
+	if loadedConfig.PublisherName != config.PublisherName || loadedConfig.PublisherEmail != config.PublisherEmail {
+		t.Errorf("Saved/loaded Publisher mismatch: got %v <%v>, want %v <%v>", loadedConfig.PublisherName, loadedConfig.PublisherEmail, config.PublisherName, config.PublisherEmail)
+	}
 	if loadedConfig.ProjectName != config.ProjectName {
 		t.Errorf("Saved/loaded ProjectName mismatch: got %v, want %v", loadedConfig.ProjectName, config.ProjectName)
 	}
@@ -331,7 +535,11 @@ func TestTemplateNameValidation(t *testing.T) {
 		{"valid starting with number", []string{"program", "--create", "1template"}, false},
 		{"invalid with spaces", []string{"program", "--create", "my template"}, true},
 		{"invalid starting with dash", []string{"program", "--create", "-template"}, true},
-		{"invalid with special chars", []string{"program", "--create", "template@123"}, true},
+		{"invalid with special chars", []string{"program", "--create", "template#123"}, true},
+		{"valid with version", []string{"program", "--create", "my-template@1.2.0-beta"}, false},
+		{"valid pinned version", []string{"program", "--template", "my-template@2"}, false},
+		{"invalid empty version", []string{"program", "--create", "template@"}, true},
+		{"invalid version", []string{"program", "--template", "template@../1"}, true},
 		{"invalid with underscore", []string{"program", "--create", "template_123"}, true},
 		{"invalid empty", []string{"program", "--create", ""}, true},
 	}
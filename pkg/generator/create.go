@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// Create updates the template at templateDir from the project at projectDir, turning the
+// project's values back into placeholders. accept decides which ambiguous substitutions
+// (see processor.FindAmbiguous) are templatized; nil rejects them all. Template files
+// that are no longer in the project are removed, apart from the manifest. Files are
+// copied concurrently, so accept may be called from several goroutines at once; a file
+// that fails does not stop the others, and the error names every one that did. If plan
+// is not nil, nothing is written and the action for each file is recorded in it instead.
+//
+// If parent is not nil the template is an overlay of the template made of those layers
+// (see overlay.Walk) and only what differs from it is stored: files that templatize to
+// the parent's contents are left out, and parent files missing from the project are
+// recorded in the manifest's deleted list.
+func Create(projectDir, templateDir string, vars *processor.TemplateVars, excluder *processor.Excluder, accept func(relPath string, hit processor.Hit) bool, parent []overlay.Layer, plan *Plan) (*processor.FileReport, error) {
+	filesToCopy := make(map[string]bool)
+	err := filepath.Walk(projectDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, _ := filepath.Rel(projectDir, path)
+		if yes, err := excluder.IsExcluded(path, info); yes {
+			// fmt.Printf("Skipping directory or file: %s\n", path)
+			if info.IsDir() {
+				relPath += string(filepath.Separator)
+			}
+			plan.add(relPath, ActionSkipped, "excluded")
+			return err
+		}
+
+		if relPath != "" {
+			filesToCopy[processor.ReverseTemplatePath(relPath, vars)] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan project: %w", err)
+	}
+
+	fmt.Printf("Found %d files/directories in source\n", len(filesToCopy))
+
+	// Only clean template directory if it already exists
+	if _, err := os.Stat(templateDir); err == nil {
+		err = filepath.Walk(templateDir, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relPath, _ := filepath.Rel(templateDir, path)
+			if relPath == "" || relPath == "." || relPath == manifest.FileName {
+				return nil
+			}
+
+			if !filesToCopy[relPath] {
+				fmt.Printf("Removing file or folder from template: %s\n", relPath)
+				plan.remove(path, relPath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to clean template directory: %w", err)
+		}
+	}
+
+	parentFiles := make(map[string]string)
+	var deleted []string
+	if parent != nil {
+		err = overlay.Walk(parent, func(path, relPath string, info fs.FileInfo) error {
+			if relPath == "." || relPath == manifest.FileName {
+				return nil
+			}
+			if filesToCopy[relPath] {
+				if !info.IsDir() {
+					parentFiles[relPath] = path
+				}
+				return nil
+			}
+
+			// Paths the project leaves out through feature selection or exclusion are
+			// inherited, not deleted
+			projectRelPath, err := processor.ApplyTemplatePath(relPath, vars)
+			if err != nil {
+				return err
+			}
+			if projectRelPath == "" {
+				return skipEntry(info)
+			}
+			if yes, _ := excluder.IsExcluded(filepath.Join(projectDir, projectRelPath), info); yes {
+				return skipEntry(info)
+			}
+
+			fmt.Printf("Deleting file or folder of the parent template: %s\n", relPath)
+			deleted = append(deleted, filepath.ToSlash(relPath))
+			if info.IsDir() {
+				plan.add(relPath+string(filepath.Separator), ActionRemoved, "deleted from the parent template")
+				return filepath.SkipDir
+			}
+			plan.add(relPath, ActionRemoved, "deleted from the parent template")
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan parent template: %w", err)
+		}
+	}
+
+	fmt.Println("Copying files to template with replacements...")
+	report := &processor.FileReport{}
+	var files []fileTask
+	var errs []error
+	err = filepath.Walk(projectDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if yes, err := excluder.IsExcluded(path, info); yes {
+			return err
+		}
+
+		relPath, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get relative path for %s: %w", path, err))
+			return nil
+		}
+
+		targetRelPath := processor.ReverseTemplatePath(relPath, vars)
+		targetDir := filepath.Dir(filepath.Join(templateDir, targetRelPath))
+		// An overlay only gets the directories of the files it stores, made as they are written
+		if parent == nil {
+			if err := plan.mkdirAll(targetDir); err != nil {
+				errs = append(errs, fmt.Errorf("failed to create directory %s: %w", targetDir, err))
+				return nil
+			}
+		}
+
+		if !info.IsDir() {
+			files = append(files, fileTask{path, relPath, targetRelPath, info.Mode(), false})
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	templatize := func(f fileTask) fileResult {
+		input, err := os.ReadFile(f.path)
+		if err != nil {
+			return fileResult{err: err}
+		}
+
+		output := input
+		binary := processor.IsBinary(f.path, input)
+		if !binary {
+			output = []byte(processor.ReverseTemplateVarsFunc(string(input), vars, func(hit processor.Hit) bool {
+				return accept != nil && accept(f.relPath, hit)
+			}))
+		}
+
+		if parentPath, ok := parentFiles[f.targetRelPath]; ok {
+			// A file the parent template already has is inherited rather than stored
+			if inherited, err := os.ReadFile(parentPath); err == nil && bytes.Equal(inherited, output) {
+				return fileResult{binary: binary, skipped: true}
+			}
+		}
+
+		targetPath := filepath.Join(templateDir, f.targetRelPath)
+		if plan != nil {
+			return fileResult{binary: binary, action: actionFor(targetPath, output)}
+		}
+		if parent != nil {
+			if err := os.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
+				return fileResult{err: err}
+			}
+		}
+		return fileResult{binary: binary, err: os.WriteFile(targetPath, output, f.mode)}
+	}
+
+	inherited := 0
+	record := func(f fileTask, r fileResult, logf func(string, ...any)) error {
+		if r.err != nil {
+			return fmt.Errorf("%s: %w", f.relPath, r.err)
+		}
+		report.Add(f.relPath, r.binary)
+		if !r.skipped {
+			plan.add(f.targetRelPath, r.action, "")
+			return nil
+		}
+
+		inherited++
+		targetPath := filepath.Join(templateDir, f.targetRelPath)
+		if _, err := os.Stat(targetPath); err == nil {
+			logf("Removing file the parent template already has: %s\n", f.targetRelPath)
+			if plan != nil {
+				plan.add(f.targetRelPath, ActionRemoved, "same as the parent template")
+			} else if err := os.Remove(targetPath); err != nil {
+				return fmt.Errorf("%s: %w", f.relPath, err)
+			}
+		} else {
+			plan.add(f.targetRelPath, ActionSkipped, "inherited")
+		}
+		return nil
+	}
+
+	errs = append(errs, processAll(context.Background(), "Copying", files, templatize, record))
+	if parent == nil {
+		return report, errors.Join(errs...)
+	}
+
+	fmt.Printf("Inherited %d files from the parent template, deleted %d\n", inherited, len(deleted))
+	if plan == nil {
+		pruneEmptyDirs(templateDir)
+		if err := os.MkdirAll(templateDir, os.ModePerm); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create template directory %s: %w", templateDir, err))
+		} else if err := manifest.SetDeleted(templateDir, deleted); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// skipEntry returns filepath.SkipDir for a directory, so a walk passes over what it
+// holds, and nil for a file
+func skipEntry(info fs.FileInfo) error {
+	if info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// pruneEmptyDirs removes the directories below root that hold nothing, deepest first
+func pruneEmptyDirs(root string) {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	for _, dir := range slices.Backward(dirs) {
+		// Only empty directories can be removed, so the error for the rest is expected
+		os.Remove(dir)
+	}
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/templates"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestCreateOverlay(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		t.Fatalf("GetUserConfigDir() unexpected error: %v", err)
+	}
+
+	parentDir := filepath.Join(configDir, "templates", "contributed", "base")
+	overlayDir := filepath.Join(configDir, "templates", "contributed", "mine")
+	projectDir := t.TempDir()
+	testutil.WriteFiles(t, parentDir, map[string]string{
+		"README.md":     "# {{PROJECT_NAME}}\n",
+		"main.go":       "package main\n",
+		"LICENSE":       "MIT\n",
+		"docs/guide.md": "guide\n",
+	})
+	testutil.WriteFiles(t, overlayDir, map[string]string{
+		"README.md":       "# {{PROJECT_NAME}}\n",
+		manifest.FileName: `{"extends": "base"}`,
+	})
+	testutil.WriteFiles(t, projectDir, map[string]string{
+		"README.md":             "# widget\n",
+		"main.go":               "package main\n\nfunc main() {}\n",
+		"LICENSE":               "MIT\n",
+		"extra.txt":             "extra\n",
+		"node_modules/pkg/x.js": "excluded\n",
+	})
+
+	vars, err := NewTemplateVars(testConfig(), manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+	excluder, err := processor.NewExcluder(projectDir)
+	if err != nil {
+		t.Fatalf("NewExcluder() unexpected error: %v", err)
+	}
+	parent, err := templates.Layers(parentDir)
+	if err != nil {
+		t.Fatalf("Layers() unexpected error: %v", err)
+	}
+
+	plan := &Plan{Mode: "create"}
+	if _, err := Create(projectDir, overlayDir, vars, excluder, nil, parent, plan); err != nil {
+		t.Fatalf("Create() dry run unexpected error: %v", err)
+	}
+	want := []PlanEntry{
+		{"LICENSE", ActionSkipped, "inherited"},
+		{"README.md", ActionRemoved, "same as the parent template"},
+		{"docs" + string(filepath.Separator), ActionRemoved, "deleted from the parent template"},
+		{"extra.txt", ActionCreate, ""},
+		{"main.go", ActionCreate, ""},
+		{"node_modules" + string(filepath.Separator), ActionSkipped, "excluded"},
+	}
+	if got := plan.sorted(); !slices.Equal(got, want) {
+		t.Errorf("Create() plan = %v, want %v", got, want)
+	}
+
+	if _, err := Create(projectDir, overlayDir, vars, excluder, nil, parent, nil); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	var stored []string
+	filepath.Walk(overlayDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(overlayDir, path)
+			stored = append(stored, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if wantStored := []string{manifest.FileName, "extra.txt", "main.go"}; !slices.Equal(stored, wantStored) {
+		t.Errorf("Create() stored %v, want only the changes %v", stored, wantStored)
+	}
+	m, err := templates.LoadManifest(overlayDir)
+	if err != nil || m.Extends != "base" || !slices.Equal(m.Deleted, []string{"docs"}) {
+		t.Errorf("LoadManifest() = %+v, %v, want extends base and docs deleted", m, err)
+	}
+
+	outDir := t.TempDir()
+	if _, err := Generate(context.Background(), overlayDir, outDir, vars, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	for rel, content := range map[string]string{
+		"README.md": "# widget\n",
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"LICENSE":   "MIT\n",
+		"extra.txt": "extra\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(outDir, rel)); err != nil || string(data) != content {
+			t.Errorf("Generate() %s = %q, %v, want %q", rel, data, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "docs")); !os.IsNotExist(err) {
+		t.Errorf("Generate() wrote docs, which the overlay deletes")
+	}
+}
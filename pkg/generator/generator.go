@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/templates"
+)
+
+// Generate renders every file of the template at templateDir, layered over the templates
+// it extends, into projectDir. Existing files listed in cfg.PreserveFiles are left alone;
+// cfg may be nil. The output is staged and only moved into projectDir once every file has
+// rendered, so if anything fails or ctx is cancelled the project is left as it was. Files
+// are rendered concurrently, and the error names every file that failed, not just the
+// first. Files about to be replaced are saved to bak, which may be nil. If lock is not
+// nil, the project's SnapshotFile and lock, as its LockFile, are staged and committed
+// with the files. If plan is not nil, nothing is written and the action for each file is
+// recorded in it instead.
+func Generate(ctx context.Context, templateDir, projectDir string, vars *processor.TemplateVars, cfg *config.Config, lock *Lock, plan *Plan, bak *backup.Backup) (*processor.FileReport, error) {
+	var stage *Stage
+	if plan == nil {
+		var err error
+		if stage, err = NewStage(projectDir); err != nil {
+			return nil, err
+		}
+		stage.backup = bak
+		defer stage.Discard()
+	}
+
+	layers, err := templates.Layers(templateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &processor.FileReport{}
+	var files []fileTask
+	err = overlay.Walk(layers, func(path, relPath string, info fs.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if relPath == manifest.FileName {
+			// The manifest describes the template and is not part of the project
+			return nil
+		}
+
+		targetRelPath, err := processor.ApplyTemplatePath(relPath, vars)
+		if err != nil {
+			return err
+		}
+		if targetRelPath == "" {
+			fmt.Printf("Skipping path excluded by feature selection: %s\n", relPath)
+			if info.IsDir() {
+				plan.add(relPath+string(filepath.Separator), ActionSkipped, "feature selection")
+				return filepath.SkipDir
+			}
+			plan.add(relPath, ActionSkipped, "feature selection")
+			return nil
+		}
+		targetPath := filepath.Join(projectDir, targetRelPath)
+
+		if info.IsDir() {
+			if stage != nil {
+				stage.mkdirAll(targetRelPath)
+			}
+			return nil
+		}
+
+		preserved := processor.ShouldPreserve(targetPath, cfg)
+		if preserved {
+			fmt.Printf("Preserving existing file: %s\n", targetRelPath)
+			plan.add(targetRelPath, ActionPreserved, "")
+			if lock == nil || stage == nil {
+				return nil
+			}
+		}
+
+		files = append(files, fileTask{path, relPath, targetRelPath, info.Mode(), preserved})
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	render := func(f fileTask) fileResult {
+		input, err := os.ReadFile(f.path)
+		if err != nil {
+			return fileResult{err: err}
+		}
+
+		output := input
+		binary := processor.IsBinary(f.path, input)
+		if !binary {
+			content, err := processor.ApplyTemplateVars(string(input), vars)
+			if err != nil {
+				return fileResult{err: err}
+			}
+			output = []byte(content)
+		}
+
+		if stage == nil {
+			return fileResult{binary: binary, action: actionFor(filepath.Join(projectDir, f.targetRelPath), output)}
+		}
+		result := fileResult{binary: binary}
+		if lock != nil {
+			result.data = output
+		}
+		if !f.preserved {
+			result.err = stage.put(f.targetRelPath, output, f.mode)
+		}
+		return result
+	}
+
+	// The snapshot holds every rendered file, preserved ones included, as update's base
+	rendered := make(map[string]snapshotEntry)
+
+	record := func(f fileTask, r fileResult, logf func(string, ...any)) error {
+		if r.err != nil {
+			return fmt.Errorf("%s: %w", f.relPath, r.err)
+		}
+		if lock != nil {
+			rendered[filepath.ToSlash(f.targetRelPath)] = snapshotEntry{r.data, f.mode.Perm()}
+		}
+		if f.preserved {
+			return nil
+		}
+		report.Add(f.targetRelPath, r.binary)
+		if stage != nil {
+			stage.add(f.targetRelPath)
+		} else {
+			plan.add(f.targetRelPath, r.action, "")
+		}
+		return nil
+	}
+
+	if err := processAll(ctx, "Generating", files, render, record); err != nil || stage == nil {
+		return report, err
+	}
+	if lock != nil {
+		if err := stageRecord(stage, rendered, cfg, lock); err != nil {
+			return report, err
+		}
+	}
+	return report, stage.Commit(ctx)
+}
@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestGenerateFeatureBlocks(t *testing.T) {
+	templateDir := t.TempDir()
+	projectDir := t.TempDir()
+	testutil.WriteFiles(t, templateDir, map[string]string{
+		"empty.txt":                              "{% if feature \"ai\" %}\nai only\n{% end %}\n",
+		"{% if feature \"ai\" %}ai.txt{% end %}": "ai only\n",
+	})
+	vars, err := NewTemplateVars(testConfig(), manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+
+	if _, err := Generate(context.Background(), templateDir, projectDir, vars, nil, nil, nil, nil); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	// A file that renders empty is written; only a conditional name leaves a file out
+	if data, err := os.ReadFile(filepath.Join(projectDir, "empty.txt")); err != nil || len(data) != 0 {
+		t.Errorf("Generate() empty.txt = %q, %v, want an empty file", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "ai.txt")); !os.IsNotExist(err) {
+		t.Errorf("Generate() wrote ai.txt without the ai feature")
+	}
+}
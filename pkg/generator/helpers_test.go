@@ -0,0 +1,13 @@
+package generator
+
+import "github.com/TrueBlocks/create-local-app/pkg/config"
+
+// testConfig returns the config of the project the generator tests render
+func testConfig() *config.Config {
+	return &config.Config{
+		Organization: "Acme, Inc",
+		ProjectName:  "widget",
+		Github:       "github.com/acme/widget",
+		Domain:       "acme.io",
+	}
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// RunHooks runs a template's hooks in order in projectDir. Template variables in each
+// command, working directory and environment value are expanded first. A failing hook
+// whose policy is abort stops the run and returns its error; any other failure prints a
+// warning and the remaining hooks still run.
+func RunHooks(stage string, hooks []manifest.Hook, projectDir string, vars *processor.TemplateVars) error {
+	for _, h := range hooks {
+		err := runHook(h, projectDir, vars)
+		if err == nil {
+			continue
+		}
+		if h.OnFailure == manifest.FailAbort {
+			return fmt.Errorf("%s hook '%s' failed: %w", stage, h.Label(), err)
+		}
+		fmt.Printf("Warning: '%s' failed: %v\n", h.Label(), err)
+	}
+	return nil
+}
+
+// runHook expands and runs a single hook, streaming its output
+func runHook(h manifest.Hook, projectDir string, vars *processor.TemplateVars) error {
+	expand := func(value string) (string, error) {
+		return processor.ApplyTemplateVars(value, vars)
+	}
+
+	args := make([]string, len(h.Command))
+	for i, arg := range h.Command {
+		var err error
+		if args[i], err = expand(arg); err != nil {
+			return err
+		}
+	}
+
+	dir, err := expand(h.Dir)
+	if err != nil {
+		return err
+	}
+	dir = filepath.Join(projectDir, dir)
+	if rel, err := filepath.Rel(projectDir, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("dir %s is outside the project", h.Dir)
+	}
+
+	env := os.Environ()
+	for _, name := range slices.Sorted(maps.Keys(h.Env)) {
+		expanded, err := expand(h.Env[name])
+		if err != nil {
+			return err
+		}
+		env = append(env, name+"="+expanded)
+	}
+
+	fmt.Printf("Running '%s' in %s\n", strings.Join(args, " "), dir)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
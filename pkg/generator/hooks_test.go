@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+func TestRunHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(projectDir, "frontend"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	vars := &processor.TemplateVars{ProjectName: "widget"}
+
+	hooks := []manifest.Hook{
+		{Command: []string{"sh", "-c", "false"}},
+		{
+			Command: []string{"sh", "-c", `echo "$GREETING {{PROJECT_NAME}}" > out.txt`},
+			Dir:     "frontend",
+			Env:     map[string]string{"GREETING": "hello"},
+		},
+	}
+	if err := RunHooks("postGenerate", hooks, projectDir, vars); err != nil {
+		t.Fatalf("RunHooks() unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(projectDir, "frontend", "out.txt"))
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if string(got) != "hello widget\n" {
+		t.Errorf("hook wrote %q, want %q", got, "hello widget\n")
+	}
+
+	abort := []manifest.Hook{
+		{Name: "check", Command: []string{"sh", "-c", "exit 3"}, OnFailure: manifest.FailAbort},
+		{Command: []string{"sh", "-c", "touch never.txt"}},
+	}
+	if err := RunHooks("preGenerate", abort, projectDir, vars); err == nil {
+		t.Errorf("RunHooks() expected error from aborting hook")
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "never.txt")); err == nil {
+		t.Errorf("RunHooks() ran a hook after an aborting failure")
+	}
+
+	escape := []manifest.Hook{{Command: []string{"sh", "-c", "true"}, Dir: "../elsewhere", OnFailure: manifest.FailAbort}}
+	if err := RunHooks("postGenerate", escape, projectDir, vars); err == nil {
+		t.Errorf("RunHooks() expected error for a dir outside the project")
+	}
+}
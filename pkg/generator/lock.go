@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/templates"
+)
+
+// LockFile records which template, template version and values produced a project,
+// along with a hash of every generated file
+const LockFile = ".create-local-app.lock"
+
+// Lock is the contents of a project's LockFile
+type Lock struct {
+	ToolVersion     string            `json:"toolVersion"`
+	Template        string            `json:"template"`
+	TemplateVersion string            `json:"templateVersion,omitempty"`
+	TemplateDir     string            `json:"templateDir"`
+	Features        []string          `json:"features,omitempty"`
+	Variables       map[string]string `json:"variables"`
+	Files           map[string]string `json:"files"`
+}
+
+// NewLock describes a project generated by this version of the tool from the named
+// template at templateDir. Its files are filled in when it is recorded.
+func NewLock(toolVersion, templateName, templateDir string, vars *processor.TemplateVars) *Lock {
+	return &Lock{
+		ToolVersion:     toolVersion,
+		Template:        templateName,
+		TemplateVersion: templates.Version(templateDir),
+		TemplateDir:     templateDir,
+		Features:        vars.Features,
+		Variables:       vars.Tokens(),
+	}
+}
+
+// hashContent returns the hash recorded for a file's content
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// setFiles records the hash of every rendered file apart from the project's preserved
+// files, which keep their own content rather than the rendered one
+func (l *Lock) setFiles(projectDir string, files map[string]snapshotEntry, cfg *config.Config) {
+	l.Files = make(map[string]string, len(files))
+	for relPath, entry := range files {
+		if processor.ShouldPreserve(filepath.Join(projectDir, filepath.FromSlash(relPath)), cfg) {
+			continue
+		}
+		l.Files[relPath] = hashContent(entry.data)
+	}
+}
+
+// encode returns the contents of the lock's LockFile
+func (l *Lock) encode() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", LockFile, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// write saves the lock to the project's LockFile
+func (l *Lock) write(projectDir string) error {
+	data, err := l.encode()
+	if err != nil {
+		return err
+	}
+	lockPath := filepath.Join(projectDir, LockFile)
+	if err := os.WriteFile(lockPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", lockPath, err)
+	}
+	return nil
+}
+
+// ReadLock loads the project's LockFile. The error satisfies os.IsNotExist if the
+// project has none.
+func ReadLock(projectDir string) (*Lock, error) {
+	lockPath := filepath.Join(projectDir, LockFile)
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", lockPath, err)
+	}
+	return l, nil
+}
+
+// Modified returns the generated files that have been changed or deleted in the project
+// since the lock was written, ordered by path
+func (l *Lock) Modified(projectDir string) ([]string, error) {
+	var modified []string
+	for _, relPath := range slices.Sorted(maps.Keys(l.Files)) {
+		data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(relPath)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err != nil || hashContent(data) != l.Files[relPath] {
+			modified = append(modified, relPath)
+		}
+	}
+	return modified, nil
+}
+
+// record saves rendered files as the project's snapshot and the hashes of those that are
+// not preserved in its lock
+func record(projectDir string, files map[string]snapshotEntry, cfg *config.Config, lock *Lock) error {
+	if err := writeSnapshot(filepath.Join(projectDir, SnapshotFile), files); err != nil {
+		return err
+	}
+	lock.setFiles(projectDir, files, cfg)
+	return lock.write(projectDir)
+}
+
+// stageRecord stages the snapshot of the rendered files and lock, with the hashes of
+// those that are not preserved, so they are committed together with the files
+func stageRecord(stage *Stage, files map[string]snapshotEntry, cfg *config.Config, lock *Lock) error {
+	snapshotPath := stage.stagedPath(SnapshotFile)
+	if err := os.MkdirAll(filepath.Dir(snapshotPath), os.ModePerm); err != nil {
+		return err
+	}
+	if err := writeSnapshot(snapshotPath, files); err != nil {
+		return err
+	}
+	stage.add(SnapshotFile)
+
+	lock.setFiles(stage.destDir, files, cfg)
+	data, err := lock.encode()
+	if err != nil {
+		return err
+	}
+	return stage.writeFile(LockFile, data, 0644)
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestLock(t *testing.T) {
+	templateDir := t.TempDir()
+	projectDir := t.TempDir()
+	testutil.WriteFiles(t, templateDir, map[string]string{
+		"README.md":   "# {{PROJECT_NAME}}\n",
+		"src/app.go":  "package {{PROJECT_NAME}}\n",
+		"LICENSE":     "MIT\n",
+		"unused.txt":  "unused\n",
+		"changed.txt": "original\n",
+		"notes.md":    "template notes\n",
+	})
+
+	cfg := testConfig()
+	cfg.Features = []string{"ai"}
+	cfg.PreserveFiles = []string{"notes.md"}
+	vars, err := NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+	// A preserved file keeps the project's content, which the lock must not mistake for an edit
+	testutil.WriteFiles(t, projectDir, map[string]string{"notes.md": "my notes\n"})
+	if _, err := Generate(context.Background(), templateDir, projectDir, vars, cfg, NewLock("1.2.3", "test", templateDir, vars), nil, nil); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	lock, err := ReadLock(projectDir)
+	if err != nil {
+		t.Fatalf("ReadLock() unexpected error: %v", err)
+	}
+	if lock.ToolVersion != "1.2.3" || lock.Template != "test" || lock.TemplateDir != templateDir {
+		t.Errorf("ReadLock() = %+v, want tool 1.2.3 and template test at %s", lock, templateDir)
+	}
+	if lock.Variables["PROJECT_NAME"] != "widget" || !slices.Equal(lock.Features, []string{"ai"}) {
+		t.Errorf("ReadLock() variables %v and features %v do not match the generation", lock.Variables, lock.Features)
+	}
+	if got := lock.Files["src/app.go"]; got != hashContent([]byte("package widget\n")) {
+		t.Errorf("ReadLock() hash of src/app.go = %s, want the hash of the rendered file", got)
+	}
+
+	// The snapshot is update's base, so it holds the rendered preserved file too
+	snapshot, err := readSnapshot(filepath.Join(projectDir, SnapshotFile))
+	if err != nil || string(snapshot["notes.md"].data) != "template notes\n" || string(snapshot["README.md"].data) != "# widget\n" {
+		t.Errorf("readSnapshot() = %v, %v, want every rendered file", slices.Sorted(maps.Keys(snapshot)), err)
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, "changed.txt"), []byte("edited\n"), 0o644); err != nil {
+		t.Fatalf("Failed to edit changed.txt: %v", err)
+	}
+	if err := os.Remove(filepath.Join(projectDir, "unused.txt")); err != nil {
+		t.Fatalf("Failed to remove unused.txt: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "local.txt"), []byte("not generated\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write local.txt: %v", err)
+	}
+
+	modified, err := lock.Modified(projectDir)
+	if err != nil {
+		t.Fatalf("Modified() unexpected error: %v", err)
+	}
+	if want := []string{"changed.txt", "unused.txt"}; !slices.Equal(modified, want) {
+		t.Errorf("Modified() = %v, want %v", modified, want)
+	}
+
+	if _, err := ReadLock(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("ReadLock() without a lock file error = %v, want not exist", err)
+	}
+
+	// A run that fails writes neither the lock nor the snapshot
+	failedDir := t.TempDir()
+	testutil.WriteFiles(t, templateDir, map[string]string{"broken.txt": "{% if %}\n"})
+	if _, err := Generate(context.Background(), templateDir, failedDir, vars, cfg, NewLock("1.2.3", "test", templateDir, vars), nil, nil); err == nil {
+		t.Fatalf("Generate() of a broken template expected an error")
+	}
+	for _, name := range []string{LockFile, SnapshotFile} {
+		if _, err := os.Stat(filepath.Join(failedDir, name)); !os.IsNotExist(err) {
+			t.Errorf("failed Generate() wrote %s", name)
+		}
+	}
+}
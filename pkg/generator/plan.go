@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Action says what a run does with one file
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionOverwrite Action = "overwrite"
+	ActionUnchanged Action = "unchanged"
+	ActionPreserved Action = "preserved"
+	ActionSkipped   Action = "skipped"
+	ActionRemoved   Action = "removed"
+	ActionMerged    Action = "merged"
+	ActionConflict  Action = "conflict"
+)
+
+// actionOrder is the order in which actions are summarized
+var actionOrder = []Action{ActionCreate, ActionOverwrite, ActionUnchanged, ActionPreserved, ActionSkipped, ActionRemoved, ActionMerged, ActionConflict}
+
+// PlanEntry is the action for one file or directory, relative to the directory written to
+type PlanEntry struct {
+	Path   string `json:"path"`
+	Action Action `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Plan records what a run would do without writing anything to disk. Generate and
+// Create take a nil plan to write files normally.
+type Plan struct {
+	Mode        string            `json:"mode"`
+	TemplateDir string            `json:"templateDir"`
+	ProjectDir  string            `json:"projectDir"`
+	Variables   map[string]string `json:"variables"`
+	Features    []string          `json:"features"`
+	Files       []PlanEntry       `json:"files"`
+}
+
+// add records an action. It does nothing on a nil plan.
+func (p *Plan) add(relPath string, action Action, reason string) {
+	if p != nil {
+		p.Files = append(p.Files, PlanEntry{relPath, action, reason})
+	}
+}
+
+// actionFor says whether writing data to path would create, overwrite or leave it unchanged
+func actionFor(path string, data []byte) Action {
+	existing, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		return ActionCreate
+	case bytes.Equal(existing, data):
+		return ActionUnchanged
+	default:
+		return ActionOverwrite
+	}
+}
+
+// mkdirAll creates a directory unless this is a dry run
+func (p *Plan) mkdirAll(path string) error {
+	if p != nil {
+		return nil
+	}
+	return os.MkdirAll(path, os.ModePerm)
+}
+
+// remove records or performs the removal of a file or empty directory
+func (p *Plan) remove(path, relPath string) {
+	if p != nil {
+		p.add(relPath, ActionRemoved, "")
+		return
+	}
+	os.Remove(path)
+}
+
+// sorted returns the entries ordered by path
+func (p *Plan) sorted() []PlanEntry {
+	return slices.SortedStableFunc(slices.Values(p.Files), func(a, b PlanEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
+
+// Print lists every file with its action, followed by a count of each action
+func (p *Plan) Print() {
+	target := p.ProjectDir
+	if p.Mode == "create" {
+		target = p.TemplateDir
+	}
+	fmt.Printf("Dry run: %s plan for %s (nothing was written)\n", p.Mode, target)
+	PrintEntries(p.sorted())
+}
+
+// PrintEntries lists each entry with its action, followed by a count of each action
+func PrintEntries(entries []PlanEntry) {
+	counts := make(map[Action]int)
+	for _, e := range entries {
+		counts[e.Action]++
+		if e.Reason != "" {
+			fmt.Printf("    %-10s %s (%s)\n", e.Action, e.Path, e.Reason)
+		} else {
+			fmt.Printf("    %-10s %s\n", e.Action, e.Path)
+		}
+	}
+
+	var summary []string
+	for _, action := range actionOrder {
+		if counts[action] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	fmt.Println("Summary:", strings.Join(summary, ", "))
+}
+
+// WriteJSON writes the plan as indented JSON with its entries ordered by path
+func (p *Plan) WriteJSON(w io.Writer) error {
+	out := *p
+	out.Files = p.sorted()
+	if out.Features == nil {
+		out.Features = []string{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
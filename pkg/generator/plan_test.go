@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestPlan(t *testing.T) {
+	cfg := testConfig()
+	cfg.PreserveFiles = []string{"notes.md"}
+	vars, err := NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+
+	t.Run("generate", func(t *testing.T) {
+		templateDir := t.TempDir()
+		projectDir := t.TempDir()
+		testutil.WriteFiles(t, templateDir, map[string]string{
+			"README.md": "# {{PROJECT_NAME}}\n",
+			"LICENSE":   "by {{ORG_NAME}}\n",
+			"notes.md":  "template notes\n",
+			"new.txt":   "new\n",
+			"{% if feature \"ai\" %}ai{% end %}/x.go": "package ai\n",
+		})
+		testutil.WriteFiles(t, projectDir, map[string]string{
+			"README.md": "# widget\n",
+			"LICENSE":   "by someone else\n",
+			"notes.md":  "my notes\n",
+		})
+
+		plan := &Plan{Mode: "generate"}
+		if _, err := Generate(context.Background(), templateDir, projectDir, vars, cfg, nil, plan, nil); err != nil {
+			t.Fatalf("Generate() unexpected error: %v", err)
+		}
+
+		want := []PlanEntry{
+			{"LICENSE", ActionOverwrite, ""},
+			{"README.md", ActionUnchanged, ""},
+			{"new.txt", ActionCreate, ""},
+			{"notes.md", ActionPreserved, ""},
+			{"{% if feature \"ai\" %}ai{% end %}/", ActionSkipped, "feature selection"},
+		}
+		if got := plan.sorted(); !slices.Equal(got, want) {
+			t.Errorf("Generate() plan = %v, want %v", got, want)
+		}
+		if _, err := os.Stat(filepath.Join(projectDir, "new.txt")); !os.IsNotExist(err) {
+			t.Errorf("Generate() with a plan wrote new.txt")
+		}
+		if data, _ := os.ReadFile(filepath.Join(projectDir, "LICENSE")); string(data) != "by someone else\n" {
+			t.Errorf("Generate() with a plan changed LICENSE to %q", data)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		templateDir := t.TempDir()
+		projectDir := t.TempDir()
+		testutil.WriteFiles(t, projectDir, map[string]string{
+			"README.md":             "# widget\n",
+			"node_modules/pkg/x.js": "excluded\n",
+		})
+		testutil.WriteFiles(t, templateDir, map[string]string{
+			"README.md":       "# {{PROJECT_NAME}}\n",
+			"stale.txt":       "left over\n",
+			manifest.FileName: `{"name": "test"}`,
+		})
+		excluder, err := processor.NewExcluder(projectDir)
+		if err != nil {
+			t.Fatalf("NewExcluder() unexpected error: %v", err)
+		}
+
+		plan := &Plan{Mode: "create"}
+		if _, err := Create(projectDir, templateDir, vars, excluder, nil, nil, plan); err != nil {
+			t.Fatalf("Create() unexpected error: %v", err)
+		}
+
+		want := []PlanEntry{
+			{"README.md", ActionUnchanged, ""},
+			{"node_modules" + string(filepath.Separator), ActionSkipped, "excluded"},
+			{"stale.txt", ActionRemoved, ""},
+		}
+		if got := plan.sorted(); !slices.Equal(got, want) {
+			t.Errorf("Create() plan = %v, want %v", got, want)
+		}
+		if _, err := os.Stat(filepath.Join(templateDir, "stale.txt")); err != nil {
+			t.Errorf("Create() with a plan removed stale.txt: %v", err)
+		}
+	})
+}
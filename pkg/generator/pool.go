@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// workers is the number of files processed at the same time
+var workers = runtime.NumCPU()
+
+// progressInterval is how often the progress line is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// fileTask is one file for the worker pool to read, transform and write
+type fileTask struct {
+	path          string // the file read
+	relPath       string // path relative to the directory read from
+	targetRelPath string // path relative to the directory written to
+	mode          fs.FileMode
+	preserved     bool // the file is rendered for the lock but not written
+}
+
+// fileResult is the outcome of a fileTask
+type fileResult struct {
+	binary  bool
+	skipped bool   // the file was left out of the output
+	action  Action // for a dry run, what writing the file would do
+	data    []byte // the rendered content, kept when the run records a lock
+	err     error
+}
+
+// processAll calls work for every item on a bounded pool of goroutines, then calls finish
+// for each item in order, on the calling goroutine, as soon as it and every item before
+// it are done. finish prints through logf, so its output comes out in the same order
+// however the work was scheduled and never runs into the progress line. The errors
+// finish returns are collected and joined rather than stopping the run, so every failing
+// file is reported. No new work is started once ctx is cancelled, and ctx's error is
+// returned after the running work has finished.
+func processAll[T, R any](ctx context.Context, label string, items []T, work func(T) R, finish func(item T, result R, logf func(format string, args ...any)) error) error {
+	results := make([]R, len(items))
+	done := make([]chan struct{}, len(items))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = work(items[i])
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range items {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	progress := newProgress(label, len(items))
+	var errs []error
+	for i, item := range items {
+		select {
+		case <-done[i]:
+		case <-ctx.Done():
+			progress.clear()
+			wg.Wait()
+			return ctx.Err()
+		}
+		if err := finish(item, results[i], progress.logf); err != nil {
+			errs = append(errs, err)
+		}
+		progress.step()
+	}
+	progress.clear()
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// progress draws a single, constantly rewritten line counting the files processed and
+// the rate. It only draws when standard output is a terminal, so logs and piped output
+// are not cluttered.
+type progress struct {
+	out   io.Writer
+	label string
+	total int
+	count int
+	start time.Time
+	drawn time.Time
+}
+
+// newProgress starts counting towards total files
+func newProgress(label string, total int) *progress {
+	p := &progress{label: label, total: total, start: time.Now()}
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		p.out = os.Stdout
+	}
+	return p
+}
+
+// step counts one more file, redrawing the line if it has not been drawn recently
+func (p *progress) step() {
+	p.count++
+	if p.out == nil || time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = time.Now()
+	rate := float64(p.count) / max(time.Since(p.start).Seconds(), 0.001)
+	fmt.Fprintf(p.out, "\r\033[K%s %d/%d files (%.0f files/s)", p.label, p.count, p.total, rate)
+}
+
+// logf prints a line of output, erasing the progress line first
+func (p *progress) logf(format string, args ...any) {
+	p.clear()
+	p.drawn = time.Time{}
+	fmt.Printf(format, args...)
+}
+
+// clear erases the line, if it was ever drawn
+func (p *progress) clear() {
+	if p.out != nil && !p.drawn.IsZero() {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+}
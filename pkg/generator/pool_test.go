@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProcessAll(t *testing.T) {
+	items := make([]int, 200)
+	for i := range items {
+		items[i] = i
+	}
+	// Later items finish first, so any ordering comes from processAll
+	work := func(i int) int {
+		time.Sleep(time.Duration(len(items)-i) * time.Microsecond)
+		return i * i
+	}
+
+	t.Run("ordered and aggregated", func(t *testing.T) {
+		var got []int
+		err := processAll(context.Background(), "Testing", items, work, func(i, square int, logf func(string, ...any)) error {
+			if square != i*i {
+				t.Errorf("result for %d = %d, want %d", i, square, i*i)
+			}
+			got = append(got, i)
+			if i%50 == 7 {
+				return fmt.Errorf("item %d failed", i)
+			}
+			return nil
+		})
+		if !slices.Equal(got, items) {
+			t.Errorf("finish saw items out of order: %v", got)
+		}
+		for _, want := range []string{"item 7 failed", "item 57 failed", "item 107 failed", "item 157 failed"} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("processAll() error = %v, want it to include %q", err, want)
+			}
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var started atomic.Int32
+		err := processAll(ctx, "Testing", items, func(i int) int {
+			if started.Add(1) == 10 {
+				cancel()
+			}
+			return work(i)
+		}, func(int, int, func(string, ...any)) error { return nil })
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("processAll() error = %v, want context.Canceled", err)
+		}
+		if n := started.Load(); n == int32(len(items)) {
+			t.Errorf("processAll() started all %d items after being cancelled", n)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// SnapshotFile is the archive in a generated project holding the template as it was
+// rendered for that project. Update uses it as the common base of a three-way merge.
+const SnapshotFile = ".create-local-app.base.tar.gz"
+
+// snapshotEntry is one rendered file
+type snapshotEntry struct {
+	data []byte
+	mode fs.FileMode
+}
+
+// render renders the template at templateDir into memory, keyed by slash-separated path
+func render(templateDir string, vars *processor.TemplateVars) (map[string]snapshotEntry, error) {
+	renderDir, err := os.MkdirTemp("", "create-local-app-render-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(renderDir)
+
+	if _, err := Generate(context.Background(), templateDir, renderDir, vars, nil, nil, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	files := make(map[string]snapshotEntry)
+	err = filepath.Walk(renderDir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(renderDir, path)
+		files[filepath.ToSlash(relPath)] = snapshotEntry{data, info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rendered template: %w", err)
+	}
+	return files, nil
+}
+
+// writeSnapshot writes files to a gzipped tar archive, sorted by path and without
+// timestamps so an unchanged render produces an identical archive
+func writeSnapshot(path string, files map[string]snapshotEntry) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer out.Close()
+
+	gzWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzWriter)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		header := &tar.Header{
+			Name:    name,
+			Mode:    int64(files[name].mode),
+			Size:    int64(len(files[name].data)),
+			ModTime: time.Unix(0, 0),
+			Format:  tar.FormatPAX,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if _, err := tarWriter.Write(files[name].data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return out.Close()
+}
+
+// readSnapshot reads an archive written by writeSnapshot. The error wraps
+// fs.ErrNotExist if there is no archive.
+func readSnapshot(path string) (map[string]snapshotEntry, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	gzReader, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer gzReader.Close()
+
+	files := make(map[string]snapshotEntry)
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		files[header.Name] = snapshotEntry{data, fs.FileMode(header.Mode).Perm()}
+	}
+	return files, nil
+}
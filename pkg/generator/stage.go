@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
+)
+
+// StagePrefix starts the name of the temporary directory a Stage keeps in its destination
+const StagePrefix = ".create-local-app-staging-"
+
+// Stage collects the files of a run in a temporary directory inside the destination and
+// moves them into place together. If any move fails, the ones already made are undone,
+// so the destination is either fully updated or left as it was.
+type Stage struct {
+	destDir string
+	dir     string
+	files   []string // relative paths of staged files, in the order they were written
+	dirs    []string // relative paths of directories the run creates, even if empty
+	backup  *backup.Backup
+}
+
+// NewStage creates the staging directory for a run that writes into destDir
+func NewStage(destDir string) (*Stage, error) {
+	dir, err := os.MkdirTemp(destDir, StagePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return &Stage{destDir: destDir, dir: dir}, nil
+}
+
+// stagedPath is where a file is kept until it is committed
+func (s *Stage) stagedPath(relPath string) string {
+	return filepath.Join(s.dir, "files", relPath)
+}
+
+// backupPath is where a file being replaced is kept until the commit succeeds
+func (s *Stage) backupPath(relPath string) string {
+	return filepath.Join(s.dir, "backup", relPath)
+}
+
+// writeFile stages a file to be written at relPath
+func (s *Stage) writeFile(relPath string, data []byte, mode fs.FileMode) error {
+	if err := s.put(relPath, data, mode); err != nil {
+		return err
+	}
+	s.add(relPath)
+	return nil
+}
+
+// put writes a file into the staging directory without adding it to the run. Unlike
+// writeFile it is safe to call from several goroutines.
+func (s *Stage) put(relPath string, data []byte, mode fs.FileMode) error {
+	path := s.stagedPath(relPath)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, mode)
+}
+
+// add includes a file already put into the staging directory in the run
+func (s *Stage) add(relPath string) {
+	s.files = append(s.files, relPath)
+}
+
+// mkdirAll stages a directory to be created at relPath
+func (s *Stage) mkdirAll(relPath string) {
+	s.dirs = append(s.dirs, relPath)
+}
+
+// Commit moves every staged file into place, stopping and undoing the moves already
+// made if one fails or ctx is cancelled. Files whose content is unchanged are left
+// alone; the others are saved to the stage's backup, if it has one, before they are
+// replaced. The staging directory is removed either way.
+func (s *Stage) Commit(ctx context.Context) error {
+	defer s.Discard()
+
+	var created, moved, backedUp []string
+	rollback := func(cause error) error {
+		for _, relPath := range slices.Backward(moved) {
+			os.Remove(filepath.Join(s.destDir, relPath))
+		}
+		for _, relPath := range slices.Backward(backedUp) {
+			if err := os.Rename(s.backupPath(relPath), filepath.Join(s.destDir, relPath)); err != nil {
+				cause = errors.Join(cause, fmt.Errorf("failed to restore %s: %w", relPath, err))
+			}
+		}
+		for _, dir := range slices.Backward(created) {
+			os.Remove(dir)
+		}
+		return cause
+	}
+
+	for _, relPath := range s.dirs {
+		dirs, err := mkdirAllNew(filepath.Join(s.destDir, relPath))
+		created = append(created, dirs...)
+		if err != nil {
+			return rollback(fmt.Errorf("failed to create %s: %w", relPath, err))
+		}
+	}
+
+	for _, relPath := range s.files {
+		if err := ctx.Err(); err != nil {
+			return rollback(err)
+		}
+
+		target := filepath.Join(s.destDir, relPath)
+		if same, err := sameContent(target, s.stagedPath(relPath)); err != nil {
+			return rollback(err)
+		} else if same {
+			continue
+		}
+		if err := s.backup.Save(relPath); err != nil {
+			return rollback(err)
+		}
+
+		dirs, err := mkdirAllNew(filepath.Dir(target))
+		created = append(created, dirs...)
+		if err != nil {
+			return rollback(fmt.Errorf("failed to create directory for %s: %w", relPath, err))
+		}
+
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			aside := s.backupPath(relPath)
+			if err := os.MkdirAll(filepath.Dir(aside), os.ModePerm); err != nil {
+				return rollback(fmt.Errorf("failed to set aside %s: %w", relPath, err))
+			}
+			if err := os.Rename(target, aside); err != nil {
+				return rollback(fmt.Errorf("failed to set aside %s: %w", relPath, err))
+			}
+			backedUp = append(backedUp, relPath)
+		}
+
+		if err := os.Rename(s.stagedPath(relPath), target); err != nil {
+			return rollback(fmt.Errorf("failed to move %s into place: %w", relPath, err))
+		}
+		moved = append(moved, relPath)
+	}
+	return nil
+}
+
+// sameContent reports whether target already holds exactly the staged file's content
+func sameContent(target, staged string) (bool, error) {
+	info, err := os.Lstat(target)
+	if err != nil || !info.Mode().IsRegular() {
+		return false, nil
+	}
+	want, err := os.ReadFile(staged)
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != int64(len(want)) {
+		return false, nil
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(got, want), nil
+}
+
+// Discard removes the staging directory and everything still in it
+func (s *Stage) Discard() error {
+	return os.RemoveAll(s.dir)
+}
+
+// mkdirAllNew creates dir and any missing parents, returning the directories it created
+// from the outermost in
+func mkdirAllNew(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || d == filepath.Dir(d) {
+			break
+		}
+		missing = append(missing, d)
+	}
+	slices.Reverse(missing)
+
+	var created []string
+	for _, d := range missing {
+		if err := os.Mkdir(d, os.ModePerm); err != nil && !os.IsExist(err) {
+			return created, err
+		}
+		created = append(created, d)
+	}
+	return created, nil
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestStage(t *testing.T) {
+	// snapshotDir describes every file and directory below root
+	snapshotDir := func(root string) string {
+		var sb strings.Builder
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, _ := filepath.Rel(root, path)
+			data := []byte("<dir>")
+			if !info.IsDir() {
+				data, _ = os.ReadFile(path)
+			}
+			sb.WriteString(relPath + "=" + string(data) + "\n")
+			return nil
+		})
+		return sb.String()
+	}
+
+	cfg := testConfig()
+	vars, err := NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+
+	t.Run("render error", func(t *testing.T) {
+		templateDir := t.TempDir()
+		projectDir := t.TempDir()
+		testutil.WriteFiles(t, templateDir, map[string]string{
+			"README.md":     "# {{PROJECT_NAME}}\n",
+			"src/main.go":   "package main\n",
+			"z/broken.txt":  "{% .NoSuchValue %}\n",
+			"a/broken.txt":  "{% if %}\n",
+			"empty/.keep":   "",
+			"assets/a.json": "{}\n",
+		})
+		testutil.WriteFiles(t, projectDir, map[string]string{"README.md": "mine\n"})
+		before := snapshotDir(projectDir)
+
+		_, err := Generate(context.Background(), templateDir, projectDir, vars, nil, nil, nil, nil)
+		for _, name := range []string{"z/broken.txt", "a/broken.txt"} {
+			if err == nil || !strings.Contains(err.Error(), filepath.FromSlash(name)) {
+				t.Fatalf("Generate() error = %v, want an error naming %s", err, name)
+			}
+		}
+		if after := snapshotDir(projectDir); after != before {
+			t.Errorf("Generate() changed the project:\n%s\nwant:\n%s", after, before)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		templateDir := t.TempDir()
+		projectDir := t.TempDir()
+		testutil.WriteFiles(t, templateDir, map[string]string{"README.md": "# {{PROJECT_NAME}}\n"})
+		before := snapshotDir(projectDir)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := Generate(ctx, templateDir, projectDir, vars, nil, nil, nil, nil); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Generate() error = %v, want context.Canceled", err)
+		}
+		if after := snapshotDir(projectDir); after != before {
+			t.Errorf("Generate() changed the project:\n%s\nwant:\n%s", after, before)
+		}
+	})
+
+	t.Run("failed move rolls back", func(t *testing.T) {
+		projectDir := t.TempDir()
+		testutil.WriteFiles(t, projectDir, map[string]string{
+			"a.txt":         "original\n",
+			"blocked/x.txt": "a directory where a file should go\n",
+		})
+		before := snapshotDir(projectDir)
+
+		stage, err := NewStage(projectDir)
+		if err != nil {
+			t.Fatalf("NewStage() unexpected error: %v", err)
+		}
+		stage.mkdirAll("new/empty")
+		for _, relPath := range []string{"a.txt", filepath.Join("deep", "b.txt"), "blocked"} {
+			if err := stage.writeFile(relPath, []byte("generated\n"), 0o644); err != nil {
+				t.Fatalf("writeFile(%s) unexpected error: %v", relPath, err)
+			}
+		}
+
+		err = stage.Commit(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "blocked") {
+			t.Fatalf("Commit() error = %v, want an error naming blocked", err)
+		}
+		if after := snapshotDir(projectDir); after != before {
+			t.Errorf("Commit() did not roll back:\n%s\nwant:\n%s", after, before)
+		}
+	})
+	t.Run("left-over stage is excluded", func(t *testing.T) {
+		projectDir := t.TempDir()
+		stage, err := NewStage(projectDir)
+		if err != nil {
+			t.Fatalf("NewStage() unexpected error: %v", err)
+		}
+		defer stage.Discard()
+		if err := stage.writeFile("README.md", []byte("generated\n"), 0o644); err != nil {
+			t.Fatalf("writeFile() unexpected error: %v", err)
+		}
+
+		// A killed run never removes its stage, which --create must not take for project files
+		excluder, err := processor.NewExcluder(projectDir)
+		if err != nil {
+			t.Fatalf("NewExcluder() unexpected error: %v", err)
+		}
+		info, err := os.Stat(stage.dir)
+		if err != nil {
+			t.Fatalf("Stat() unexpected error: %v", err)
+		}
+		if excluded, _ := excluder.IsExcluded(stage.dir, info); !excluded {
+			t.Errorf("IsExcluded(%s) = false, want the staging directory excluded", filepath.Base(stage.dir))
+		}
+	})
+}
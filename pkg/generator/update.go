@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/merge"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// RejectSuffix is added to the name of a file holding a template version that could not
+// be merged into the project
+const RejectSuffix = ".rej"
+
+// Update brings the project at projectDir up to date with the template at templateDir.
+// The project's SnapshotFile is the common base: files only the template changed are
+// replaced, files both sides changed are merged line by line, and conflicting lines are
+// marked in place. A conflicting binary file, or a file the project deleted, gets the
+// template's version in a RejectSuffix file instead. Files listed in cfg.PreserveFiles
+// are left alone. Every file is saved to bak, which may be nil, before it is changed. The
+// snapshot is replaced with the new render and lock is recorded as the project's
+// LockFile. The changes are returned ordered by path.
+func Update(templateDir, projectDir string, vars *processor.TemplateVars, cfg *config.Config, lock *Lock, bak *backup.Backup) ([]PlanEntry, error) {
+	theirs, err := render(templateDir, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotPath := filepath.Join(projectDir, SnapshotFile)
+	base, err := readSnapshot(snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("Warning: %s not found, so every local change to a template file will be reported as a conflict\n", SnapshotFile)
+		base = make(map[string]snapshotEntry)
+	} else if err != nil {
+		return nil, err
+	}
+
+	all := maps.Clone(base)
+	maps.Copy(all, theirs)
+
+	var changes []PlanEntry
+	for _, relPath := range slices.Sorted(maps.Keys(all)) {
+		entry, err := updateFile(projectDir, relPath, base, theirs, cfg, bak)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", relPath, err)
+		}
+		if entry != nil {
+			changes = append(changes, *entry)
+		}
+	}
+
+	if err := bak.Save(SnapshotFile); err != nil {
+		return nil, err
+	}
+	if err := bak.Save(LockFile); err != nil {
+		return nil, err
+	}
+	if err := record(projectDir, theirs, cfg, lock); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// updateFile applies the template's change to one file, returning what it did or nil
+// if nothing needed to change
+func updateFile(projectDir, relPath string, base, theirs map[string]snapshotEntry, cfg *config.Config, bak *backup.Backup) (*PlanEntry, error) {
+	b, inBase := base[relPath]
+	t, inTheirs := theirs[relPath]
+	if inBase && inTheirs && bytes.Equal(b.data, t.data) {
+		return nil, nil
+	}
+
+	path := filepath.Join(projectDir, filepath.FromSlash(relPath))
+	if processor.ShouldPreserve(path, cfg) {
+		return &PlanEntry{relPath, ActionPreserved, ""}, nil
+	}
+
+	ours, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	switch {
+	case !inTheirs:
+		if !exists {
+			return nil, nil
+		}
+		if !bytes.Equal(ours, b.data) {
+			return &PlanEntry{relPath, ActionConflict, "removed from the template but changed in the project"}, nil
+		}
+		if err := bak.Save(filepath.FromSlash(relPath)); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+		return &PlanEntry{relPath, ActionRemoved, ""}, nil
+
+	case !exists:
+		if inBase {
+			return reject(path, relPath, t, "deleted in the project but changed in the template", bak)
+		}
+		if err := bak.Save(filepath.FromSlash(relPath)); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, t.data, t.mode); err != nil {
+			return nil, err
+		}
+		return &PlanEntry{relPath, ActionCreate, ""}, nil
+
+	case bytes.Equal(ours, t.data):
+		return nil, nil
+
+	case inBase && bytes.Equal(ours, b.data):
+		if err := bak.Save(filepath.FromSlash(relPath)); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, t.data, t.mode); err != nil {
+			return nil, err
+		}
+		return &PlanEntry{relPath, ActionOverwrite, ""}, nil
+
+	case processor.IsBinary(path, ours) || processor.IsBinary(path, t.data):
+		return reject(path, relPath, t, "binary file changed in both the project and the template", bak)
+	}
+
+	merged, conflicts := merge.Merge(string(b.data), string(ours), string(t.data))
+	if err := bak.Save(filepath.FromSlash(relPath)); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(merged), t.mode); err != nil {
+		return nil, err
+	}
+	if conflicts > 0 {
+		return &PlanEntry{relPath, ActionConflict, fmt.Sprintf("%d conflicting regions marked in the file", conflicts)}, nil
+	}
+	return &PlanEntry{relPath, ActionMerged, ""}, nil
+}
+
+// reject writes the template's version of a file that could not be merged next to it,
+// recording the rejected file in bak first
+func reject(path, relPath string, t snapshotEntry, reason string, bak *backup.Backup) (*PlanEntry, error) {
+	if err := bak.Save(filepath.FromSlash(relPath) + RejectSuffix); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+RejectSuffix, t.data, t.mode); err != nil {
+		return nil, err
+	}
+	return &PlanEntry{relPath, ActionConflict, reason + "; template version in " + filepath.Base(path) + RejectSuffix}, nil
+}
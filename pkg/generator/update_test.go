@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/backup"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestUpdate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	templateDir := t.TempDir()
+	projectDir := t.TempDir()
+	read := func(rel string) string {
+		data, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	cfg := testConfig()
+	cfg.PreserveFiles = []string{"notes.md"}
+	vars, err := NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+
+	// Generate the original project and its snapshot
+	testutil.WriteFiles(t, templateDir, map[string]string{
+		"README.md":   "# {{PROJECT_NAME}}\n\nintro\n\nusage\n",
+		"main.go":     "package main\n\nfunc main() {\n}\n",
+		"config.txt":  "a=1\n",
+		"old.txt":     "old\n",
+		"edited.txt":  "keep\n",
+		"deleted.txt": "one\n",
+		"notes.md":    "notes\n",
+	})
+	if _, err := Generate(context.Background(), templateDir, projectDir, vars, cfg, NewLock("test", "test", templateDir, vars), nil, nil); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	// Change the project and the template independently
+	testutil.WriteFiles(t, projectDir, map[string]string{
+		"README.md":  "# widget\n\nintro\n\nusage\n\nlocal section\n",
+		"config.txt": "a=local\n",
+		"edited.txt": "local edit\n",
+		"notes.md":   "my notes\n",
+	})
+	if err := os.Remove(filepath.Join(projectDir, "deleted.txt")); err != nil {
+		t.Fatalf("Failed to remove deleted.txt: %v", err)
+	}
+	testutil.WriteFiles(t, templateDir, map[string]string{
+		"README.md":   "# {{PROJECT_NAME}}\n\nbetter intro\n\nusage\n",
+		"main.go":     "package main\n\nfunc main() {\n\trun()\n}\n",
+		"config.txt":  "a=2\n",
+		"new.txt":     "new\n",
+		"deleted.txt": "two\n",
+		"notes.md":    "new notes\n",
+	})
+	for _, name := range []string{"old.txt", "edited.txt"} {
+		if err := os.Remove(filepath.Join(templateDir, name)); err != nil {
+			t.Fatalf("Failed to remove %s: %v", name, err)
+		}
+	}
+
+	bak := backup.New(projectDir, "update")
+	changes, err := Update(templateDir, projectDir, vars, cfg, NewLock("test", "test", templateDir, vars), bak)
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+
+	want := []PlanEntry{
+		{"README.md", ActionMerged, ""},
+		{"config.txt", ActionConflict, "1 conflicting regions marked in the file"},
+		{"deleted.txt", ActionConflict, "deleted in the project but changed in the template; template version in deleted.txt.rej"},
+		{"edited.txt", ActionConflict, "removed from the template but changed in the project"},
+		{"main.go", ActionOverwrite, ""},
+		{"new.txt", ActionCreate, ""},
+		{"notes.md", ActionPreserved, ""},
+		{"old.txt", ActionRemoved, ""},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("Update() = %v, want %v", changes, want)
+	}
+
+	files := map[string]string{
+		"README.md":       "# widget\n\nbetter intro\n\nusage\n\nlocal section\n",
+		"config.txt":      "<<<<<<< project\na=local\n=======\na=2\n>>>>>>> template\n",
+		"deleted.txt":     "<missing>",
+		"deleted.txt.rej": "two\n",
+		"edited.txt":      "local edit\n",
+		"main.go":         "package main\n\nfunc main() {\n\trun()\n}\n",
+		"new.txt":         "new\n",
+		"notes.md":        "my notes\n",
+		"old.txt":         "<missing>",
+	}
+	for rel, content := range files {
+		if got := read(rel); got != content {
+			t.Errorf("after Update() %s = %q, want %q", rel, got, content)
+		}
+	}
+
+	// Only the files the update wrote or removed are in the backup
+	wantSaved := []string{SnapshotFile, LockFile, "README.md", "config.txt", "main.go", "old.txt"}
+	if got := slices.Sorted(slices.Values(bak.Saved)); !slices.Equal(got, wantSaved) {
+		t.Errorf("backup saved %v, want %v", got, wantSaved)
+	}
+	if got, wantAdded := slices.Sorted(slices.Values(bak.Added)), []string{"deleted.txt.rej", "new.txt"}; !slices.Equal(got, wantAdded) {
+		t.Errorf("backup added %v, want %v", got, wantAdded)
+	}
+
+	// The new render is the base for the next update
+	changes, err = Update(templateDir, projectDir, vars, cfg, NewLock("test", "test", templateDir, vars), nil)
+	if err != nil {
+		t.Fatalf("second Update() unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("second Update() = %v, want no changes", changes)
+	}
+}
@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// Chifra is the module path substituted for {{CHIFRA}}
+const Chifra = "github.com/TrueBlocks/trueblocks-chifra/v6"
+
+// NewTemplateVars builds the values substituted into a template from a configuration
+// and the template's manifest. Manifest variables that are not built in are taken from
+// the configuration, then derived variables are computed in declaration order.
+func NewTemplateVars(cfg *config.Config, m *manifest.Manifest) (*processor.TemplateVars, error) {
+	orgName := strings.TrimSpace(strings.Split(cfg.Organization, ",")[0])
+
+	projectProper := cfg.ProjectName
+	if len(cfg.ProjectName) > 0 {
+		projectProper = strings.ToUpper(cfg.ProjectName[0:1]) + cfg.ProjectName[1:]
+	}
+
+	vars := &processor.TemplateVars{
+		ProjectName:    cfg.ProjectName,
+		ProjectProper:  projectProper,
+		PublisherName:  cfg.PublisherName,
+		PublisherEmail: cfg.PublisherEmail,
+		Organization:   cfg.Organization,
+		OrgName:        orgName,
+		OrgLower:       strings.ToLower(orgName),
+		Slug:           strings.ToLower(orgName) + "-" + cfg.ProjectName,
+		Github:         cfg.Github,
+		Domain:         cfg.Domain,
+		Chifra:         Chifra,
+		ProjectCases:   processor.NewCaseVariants(cfg.ProjectName),
+		OrgCases:       processor.NewCaseVariants(orgName),
+		Features:       cfg.Features,
+		Extra:          make(map[string]string),
+	}
+
+	builtIns := vars.Tokens()
+	for _, v := range m.Prompted() {
+		if _, builtIn := builtIns[v.Name]; !builtIn {
+			vars.Extra[v.Name] = cfg.GetValue(v.Name)
+		}
+	}
+	for _, v := range m.Derived() {
+		value, err := processor.ApplyTemplateVars(v.Derived, vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive %s: %w", v.Name, err)
+		}
+		if err := v.Check(value); err != nil {
+			return nil, err
+		}
+		vars.Extra[v.Name] = value
+	}
+
+	return vars, nil
+}
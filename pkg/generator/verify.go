@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// Mismatch describes a file that does not survive a round trip through a template
+type Mismatch struct {
+	Path   string
+	Reason string
+}
+
+// Verify renders the template at templateDir into a temporary directory and compares
+// the result with the project at projectDir, returning every file that differs, is
+// missing or is extra. Files the excluder skips are not expected in the template, and
+// are ignored if it renders them.
+func Verify(templateDir, projectDir string, vars *processor.TemplateVars, excluder *processor.Excluder) ([]Mismatch, error) {
+	renderDir, err := os.MkdirTemp("", "create-local-app-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(renderDir)
+
+	if _, err := Generate(context.Background(), templateDir, renderDir, vars, nil, nil, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	want, err := listFiles(projectDir, excluder)
+	if err != nil {
+		return nil, err
+	}
+	got, err := listFiles(renderDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	// A template extending another still gets the files its parent has where the project
+	// excludes them, as the project never said to delete them
+	for relPath := range got {
+		if excluder == nil {
+			break
+		}
+		info, err := os.Lstat(filepath.Join(renderDir, relPath))
+		if err != nil {
+			return nil, err
+		}
+		if yes, _ := excluder.IsExcluded(filepath.Join(projectDir, relPath), info); yes {
+			delete(got, relPath)
+		}
+	}
+
+	all := maps.Clone(want)
+	maps.Copy(all, got)
+
+	var mismatches []Mismatch
+	for _, relPath := range slices.Sorted(maps.Keys(all)) {
+		switch {
+		case !got[relPath]:
+			mismatches = append(mismatches, Mismatch{relPath, "not generated by the template"})
+		case !want[relPath]:
+			mismatches = append(mismatches, Mismatch{relPath, "generated but not in the project"})
+		default:
+			reason, err := compareFiles(filepath.Join(projectDir, relPath), filepath.Join(renderDir, relPath))
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				mismatches = append(mismatches, Mismatch{relPath, reason})
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+// listFiles returns the relative path of every file below root not skipped by excluder
+func listFiles(root string, excluder *processor.Excluder) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if excluder != nil {
+			if yes, err := excluder.IsExcluded(path, info); yes {
+				return err
+			}
+		}
+		if !info.IsDir() {
+			relPath, _ := filepath.Rel(root, path)
+			files[relPath] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// compareFiles describes the first difference between the source file and the rendered
+// one, or returns "" if they are identical
+func compareFiles(sourcePath, renderedPath string) (string, error) {
+	want, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	got, err := os.ReadFile(renderedPath)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(want, got) {
+		return "", nil
+	}
+	if processor.IsBinary(sourcePath, want) {
+		return "binary content differs", nil
+	}
+
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return fmt.Sprintf("line %d: project has %q, template renders %q", i+1, w, g), nil
+		}
+	}
+	return "content differs", nil
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestVerify(t *testing.T) {
+	templateDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	testutil.WriteFiles(t, templateDir, map[string]string{
+		"README.md":                            "# {{PROJECT_NAME}}\nby {{ORG_NAME}}\n",
+		"{{SLUG}}.txt":                         "slug\n",
+		"go.mod":                               "module {{GITHUB}}\n",
+		"stale.txt":                            "left over\n",
+		manifest.FileName:                      `{"name": "test"}`,
+		"{% if feature \"ai\" %}ai{% end %}/x": "ai only\n",
+	})
+	testutil.WriteFiles(t, projectDir, map[string]string{
+		"README.md":             "# widget\nby Acme\n",
+		"acme-widget.txt":       "slug\n",
+		"go.mod":                "module github.com/acme/widget/v2\n",
+		"local.txt":             "not in template\n",
+		"node_modules/pkg/x.js": "excluded\n",
+	})
+
+	cfg := testConfig()
+	vars, err := NewTemplateVars(cfg, manifest.Default())
+	if err != nil {
+		t.Fatalf("NewTemplateVars() unexpected error: %v", err)
+	}
+	excluder, err := processor.NewExcluder(projectDir)
+	if err != nil {
+		t.Fatalf("NewExcluder() unexpected error: %v", err)
+	}
+
+	mismatches, err := Verify(templateDir, projectDir, vars, excluder)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+
+	want := []Mismatch{
+		{"go.mod", `line 1: project has "module github.com/acme/widget/v2", template renders "module github.com/acme/widget"`},
+		{"local.txt", "not generated by the template"},
+		{"stale.txt", "generated but not in the project"},
+	}
+	if !slices.Equal(mismatches, want) {
+		t.Errorf("Verify() = %v, want %v", mismatches, want)
+	}
+}
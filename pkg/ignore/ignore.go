@@ -0,0 +1,260 @@
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// rule is one compiled gitignore pattern
+type rule struct {
+	base    string // slash-separated directory the pattern is relative to ("" for the root)
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// Matcher decides whether paths below a root directory are ignored using gitignore
+// semantics: blank lines and # comments, ! negation, trailing / for directories,
+// patterns anchored by a slash, and *, ?, [...] and ** wildcards. Rules are applied in
+// layers (defaults, then the per-directory ignore files from the root down, then
+// overrides) and the last matching rule wins.
+type Matcher struct {
+	root      string
+	nested    string
+	defaults  []rule
+	perDir    map[string][]rule
+	overrides []rule
+}
+
+// New returns a Matcher for paths below root. If nestedFile is not empty (typically
+// ".gitignore"), a file of that name in any directory contributes rules for that
+// directory and everything below it.
+func New(root, nestedFile string) *Matcher {
+	return &Matcher{
+		root:   root,
+		nested: nestedFile,
+		perDir: make(map[string][]rule),
+	}
+}
+
+// AddDefaults adds patterns with the lowest precedence
+func (m *Matcher) AddDefaults(patterns []string) error {
+	rules, err := compileAll("", patterns)
+	if err != nil {
+		return err
+	}
+	m.defaults = append(m.defaults, rules...)
+	return nil
+}
+
+// AddOverrides adds patterns with the highest precedence
+func (m *Matcher) AddOverrides(patterns []string) error {
+	rules, err := compileAll("", patterns)
+	if err != nil {
+		return err
+	}
+	m.overrides = append(m.overrides, rules...)
+	return nil
+}
+
+// AddOverrideFile adds the patterns in a file at the root with the highest precedence.
+// A missing file is not an error.
+func (m *Matcher) AddOverrideFile(name string) error {
+	patterns, err := readPatterns(filepath.Join(m.root, name))
+	if err != nil {
+		return err
+	}
+	return m.AddOverrides(patterns)
+}
+
+// Match reports whether relPath (relative to the root) is ignored, either directly or
+// because one of its parent directories is
+func (m *Matcher) Match(relPath string, isDir bool) (bool, error) {
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	if relPath == "." || relPath == "" {
+		return false, nil
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		ignored, err := m.matchOne(strings.Join(parts[:i], "/"), true)
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return m.matchOne(relPath, isDir)
+}
+
+// matchOne applies every rule layer to a single path, ignoring its parents
+func (m *Matcher) matchOne(relPath string, isDir bool) (bool, error) {
+	ignored := apply(m.defaults, relPath, isDir, false)
+
+	if m.nested != "" {
+		dir := ""
+		dirs := []string{""}
+		for _, part := range strings.Split(path.Dir(relPath), "/") {
+			if part == "." {
+				break
+			}
+			dir = path.Join(dir, part)
+			dirs = append(dirs, dir)
+		}
+		for _, d := range dirs {
+			rules, err := m.dirRules(d)
+			if err != nil {
+				return false, err
+			}
+			ignored = apply(rules, relPath, isDir, ignored)
+		}
+	}
+
+	return apply(m.overrides, relPath, isDir, ignored), nil
+}
+
+// dirRules loads and caches the rules from the nested ignore file in dir
+func (m *Matcher) dirRules(dir string) ([]rule, error) {
+	if rules, ok := m.perDir[dir]; ok {
+		return rules, nil
+	}
+	patterns, err := readPatterns(filepath.Join(m.root, filepath.FromSlash(dir), m.nested))
+	if err != nil {
+		return nil, err
+	}
+	rules, err := compileAll(dir, patterns)
+	if err != nil {
+		return nil, err
+	}
+	m.perDir[dir] = rules
+	return rules, nil
+}
+
+// apply runs rules in order over relPath, returning the final ignored state
+func apply(rules []rule, relPath string, isDir, ignored bool) bool {
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		target := relPath
+		if r.base != "" {
+			if !strings.HasPrefix(relPath, r.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(relPath, r.base+"/")
+		}
+		if r.re.MatchString(target) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// readPatterns returns the lines of an ignore file, or nothing if it doesn't exist
+func readPatterns(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), nil
+}
+
+// compileAll compiles every meaningful line of patterns
+func compileAll(base string, patterns []string) ([]rule, error) {
+	var rules []rule
+	for _, line := range patterns {
+		r, ok, err := compile(base, line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// compile turns one gitignore line into a rule. ok is false for blank lines and comments.
+func compile(base, line string) (r rule, ok bool, err error) {
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false, nil
+	}
+
+	r.base = base
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A slash anywhere but the end anchors the pattern to its base directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule{}, false, nil
+	}
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	r.re, err = regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	return r, true, nil
+}
+
+// globToRegexp translates a gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && i > 0 && glob[i-1] == '/':
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package ignore
+
+import (
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestMatch(t *testing.T) {
+	patterns := []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"/root-only.txt",
+		"docs/",
+		"frontend/generated",
+		"**/cache/**",
+		"!**/cache/**/README.md",
+		"!**/cache/**/",
+		"a/**/z.txt",
+		"file[0-9].txt",
+		`\#literal`,
+		"trailing   ",
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"deep/nested/app.log", false, true},
+		{"keep.log", false, false},
+		{"deep/keep.log", false, false},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"docs", true, true},
+		{"docs", false, false},
+		{"docs/readme.md", false, true},
+		{"sub/docs/readme.md", false, true},
+		{"frontend/generated", true, true},
+		{"frontend/generated/x.ts", false, true},
+		{"other/frontend/generated", true, false},
+		{"pkg/cache/data.bin", false, true},
+		{"pkg/cache/README.md", false, false},
+		{"pkg/cache/inner", true, false},
+		{"pkg/cache", true, false},
+		{"a/z.txt", false, true},
+		{"a/b/c/z.txt", false, true},
+		{"b/a/z.txt", false, false},
+		{"file1.txt", false, true},
+		{"fileX.txt", false, false},
+		{"#literal", false, true},
+		{"trailing", false, true},
+		{"main.go", false, false},
+	}
+
+	m := New(t.TempDir(), "")
+	if err := m.AddOverrides(patterns); err != nil {
+		t.Fatalf("AddOverrides() unexpected error: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := m.Match(tt.path, tt.isDir)
+			if err != nil {
+				t.Fatalf("Match() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayers(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFiles(t, root, map[string]string{
+		".gitignore":          "*.tmp\nnode_modules\n",
+		"frontend/.gitignore": "!important.tmp\n/local\n",
+		".override":           "!node_modules\nsecret.txt\n",
+	})
+
+	m := New(root, ".gitignore")
+	if err := m.AddDefaults([]string{"node_modules", "dist", "secret.txt"}); err != nil {
+		t.Fatalf("AddDefaults() unexpected error: %v", err)
+	}
+	if err := m.AddOverrideFile(".override"); err != nil {
+		t.Fatalf("AddOverrideFile() unexpected error: %v", err)
+	}
+	if err := m.AddOverrideFile(".missing"); err != nil {
+		t.Fatalf("AddOverrideFile() with missing file unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"dist", true, true},
+		{"a.tmp", false, true},
+		{"frontend/a.tmp", false, true},
+		{"frontend/important.tmp", false, false},
+		{"important.tmp", false, true},
+		{"frontend/local", true, true},
+		{"local", true, false},
+		{"node_modules", true, false},
+		{"secret.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := m.Match(tt.path, tt.isDir)
+			if err != nil {
+				t.Fatalf("Match() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package lint
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+)
+
+// Issue is a problem found in a template. Line is 0 for problems in a path or in the manifest.
+type Issue struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// String formats the issue as path:line: message
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return i.Path + ": " + i.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Message)
+}
+
+// computedFrom lists the built-in tokens computed from each prompted built-in variable,
+// so a variable used only through one of them still counts as used
+var computedFrom = map[string][]string{
+	"ORGANIZATION": {"ORG_NAME", "ORG_LOWER", "ORG_PASCAL", "ORG_CAMEL", "ORG_SNAKE", "ORG_KEBAB", "ORG_SCREAMING", "ORG_TITLE",
+		"SLUG", "MODULE", "PACKAGES", "SAVEPKG", "APP"},
+	"PROJECT_NAME": {"PROJECT_PROPER", "PROJECT_PASCAL", "PROJECT_CAMEL", "PROJECT_SNAKE", "PROJECT_KEBAB", "PROJECT_SCREAMING", "PROJECT_TITLE",
+		"SLUG", "MODULE", "PACKAGES", "SAVEPKG", "APP"},
+	"GITHUB": {"MODULE", "PACKAGES", "SAVEPKG", "APP"},
+}
+
+// Check scans the template made of layers (see overlay.Walk) for placeholders that are
+// not known variables and for manifest variables that are never used. If vars is not nil
+// it also reports the project's literal values that were left where a placeholder belongs.
+func Check(layers []overlay.Layer, m *manifest.Manifest, vars *processor.TemplateVars) ([]Issue, error) {
+	known := (&processor.TemplateVars{}).Tokens()
+	known["FEATURES"] = ""
+	for _, v := range m.Variables {
+		known[v.Name] = ""
+	}
+
+	var issues []Issue
+	used := make(map[string]bool)
+	check := func(relPath, content string, inPath bool) {
+		lineOf := func(line int) int {
+			if inPath {
+				return 0
+			}
+			return line
+		}
+		for _, p := range processor.FindPlaceholders(content) {
+			used[p.Name] = true
+			if _, ok := known[p.Name]; !ok {
+				issues = append(issues, Issue{relPath, lineOf(p.Line), fmt.Sprintf("unknown placeholder %s", p.Name)})
+			}
+		}
+		if vars == nil {
+			return
+		}
+		for _, hit := range processor.FindLiterals(content, vars) {
+			issues = append(issues, Issue{relPath, lineOf(hit.Line), fmt.Sprintf("literal %q should probably be %s", hit.Value, hit.Token)})
+		}
+	}
+
+	err := overlay.Walk(layers, func(path, relPath string, info fs.FileInfo) error {
+		if relPath == "." || relPath == manifest.FileName {
+			return nil
+		}
+
+		// Problems in a path are reported without a line number
+		check(relPath, filepath.ToSlash(relPath), true)
+		if info.IsDir() {
+			return nil
+		}
+
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !processor.IsBinary(path, input) {
+			check(relPath, string(input), false)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan template: %w", err)
+	}
+
+	for _, v := range m.Derived() {
+		for _, p := range processor.FindPlaceholders(v.Derived) {
+			used[p.Name] = true
+		}
+	}
+	for _, v := range m.Variables {
+		if !used[v.Name] && !slices.ContainsFunc(computedFrom[v.Name], func(name string) bool { return used[name] }) {
+			issues = append(issues, Issue{manifest.FileName, 0, fmt.Sprintf("variable %s is never used", v.Name)})
+		}
+	}
+
+	slices.SortStableFunc(issues, func(a, b Issue) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return issues, nil
+}
@@ -0,0 +1,74 @@
+package lint
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
+	"github.com/TrueBlocks/create-local-app/pkg/processor"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestCheck(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"README.md":           "# {{PROJECT_NAME}} by {{ORG_NAME}}\nSee {{PROJET_NAME}} and {{GITHUB}}.\n",
+		"app.go":              "{% if feature \"ai\" %}\nconst chain = \"{% .CHAIN | upper %}\"\n{% end %}\n// {% .CHIAN %} {% .DOMAIN %}\n",
+		"{{ORG_NAMES}}/x.txt": "Built by Acme for acme.io\n",
+		"template.go":         "{{.Name}} {{ .Value }} {{OTHER_THING_NOT_OURS}\n",
+		manifest.FileName:     `{"variables": [{"name": "CHAIN", "prompt": "Chain"}, {"name": "UNUSED", "prompt": "Unused"}, {"name": "ORGANIZATION", "prompt": "Organization"}, {"name": "DOMAIN", "prompt": "Domain"}]}`,
+	}
+	testutil.WriteFiles(t, templateDir, files)
+
+	m, err := manifest.Load(templateDir)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		vars *processor.TemplateVars
+		want []string
+	}{
+		{
+			name: "placeholders and variables only",
+			want: []string{
+				".wails-template.json: variable UNUSED is never used",
+				"README.md:2: unknown placeholder PROJET_NAME",
+				"app.go:4: unknown placeholder CHIAN",
+				"{{ORG_NAMES}}: unknown placeholder ORG_NAMES",
+				"{{ORG_NAMES}}/x.txt: unknown placeholder ORG_NAMES",
+			},
+		},
+		{
+			name: "with project values",
+			vars: &processor.TemplateVars{OrgName: "Acme", Domain: "acme.io"},
+			want: []string{
+				".wails-template.json: variable UNUSED is never used",
+				"README.md:2: unknown placeholder PROJET_NAME",
+				"app.go:4: unknown placeholder CHIAN",
+				"{{ORG_NAMES}}: unknown placeholder ORG_NAMES",
+				"{{ORG_NAMES}}/x.txt: unknown placeholder ORG_NAMES",
+				`{{ORG_NAMES}}/x.txt:1: literal "Acme" should probably be {{ORG_NAME}}`,
+				`{{ORG_NAMES}}/x.txt:1: literal "acme.io" should probably be {{DOMAIN}}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := Check([]overlay.Layer{{Dir: templateDir}}, m, tt.vars)
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package manifest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the manifest file at the root of a template. It extends the Wails
+// template.json format with the variables the template needs.
+const FileName = ".wails-template.json"
+
+// Variable describes one template variable: how to prompt for it, its default value,
+// how to validate it, or how to derive it from other variables
+type Variable struct {
+	Name     string `json:"name"`
+	Prompt   string `json:"prompt,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Derived  string `json:"derived,omitempty"`
+	Validate string `json:"validate,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// Hook is a command run before or after a project is generated. Command, Dir and Env
+// values may contain template variables.
+type Hook struct {
+	Name      string            `json:"name,omitempty"`
+	Command   []string          `json:"command"`
+	Dir       string            `json:"dir,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	OnFailure string            `json:"onFailure,omitempty"`
+}
+
+// Failure policies for hooks. A warning is the default.
+const (
+	FailWarn  = "warn"
+	FailAbort = "abort"
+)
+
+// Hooks lists the commands a template runs around generation, in order
+type Hooks struct {
+	PreGenerate  []Hook `json:"preGenerate,omitempty"`
+	PostGenerate []Hook `json:"postGenerate,omitempty"`
+}
+
+// Manifest represents the contents of a template's .wails-template.json
+type Manifest struct {
+	Name        string     `json:"name,omitempty"`
+	ShortName   string     `json:"shortname,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Description string     `json:"description,omitempty"`
+	HelpURL     string     `json:"helpurl,omitempty"`
+	Version     string     `json:"version,omitempty"`
+	Extends     string     `json:"extends,omitempty"` // the template this one overlays, as name or name@version
+	Deleted     []string   `json:"deleted,omitempty"` // slash-separated paths removed from the template it extends
+	Variables   []Variable `json:"variables,omitempty"`
+	Hooks       *Hooks     `json:"hooks,omitempty"`
+}
+
+// namePattern matches a valid variable name, which doubles as its {{TOKEN}} spelling
+var namePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// versionPattern matches a valid template version, such as 1.2 or 2.0.0-beta
+var versionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.-]*$`)
+
+// ValidVersion reports whether version can be used as a template version
+func ValidVersion(version string) bool {
+	return versionPattern.MatchString(version)
+}
+
+// Default returns the manifest used by templates that don't declare their own variables.
+// It describes the four values create-local-app has always asked for.
+func Default() *Manifest {
+	return &Manifest{
+		Variables: []Variable{
+			{Name: "ORGANIZATION", Prompt: "Organization", Required: true},
+			{Name: "PUBLISHER_NAME", Prompt: "Publisher Name"},
+			{Name: "PUBLISHER_EMAIL", Prompt: "Publisher Email", Validate: `^[^@\s]+@[^@\s]+$`},
+			{Name: "PROJECT_NAME", Prompt: "Project Name", Required: true},
+			{Name: "GITHUB", Prompt: "Github", Required: true, Validate: `^\S+$`},
+			{Name: "DOMAIN", Prompt: "Domain", Required: true},
+		},
+		Hooks: DefaultHooks(),
+	}
+}
+
+// DefaultHooks returns the hooks used by templates whose manifest doesn't declare any:
+// the steps every Wails project needs after it is generated
+func DefaultHooks() *Hooks {
+	return &Hooks{
+		PostGenerate: []Hook{
+			{Command: []string{"yarn", "install"}, OnFailure: FailWarn},
+			{Command: []string{"wails", "generate", "modules"}, OnFailure: FailWarn},
+		},
+	}
+}
+
+// Load reads the manifest from templateDir, falling back to Default if the template has
+// no manifest. A manifest that declares no variables uses the default variables, and
+// one without a hooks entry uses DefaultHooks.
+func Load(templateDir string) (*Manifest, error) {
+	return LoadLayers(templateDir)
+}
+
+// LoadLayers reads the manifests of the layers of an overlay template, base first, and
+// combines them: each entry a manifest sets replaces the one from the layers below it.
+// The version, extends and deleted entries describe only the last layer and are taken
+// from it alone. Defaults are filled in as Load does.
+func LoadLayers(templateDirs ...string) (*Manifest, error) {
+	m := &Manifest{}
+	manifestPath := ""
+	for i, templateDir := range templateDirs {
+		layer, layerPath, err := read(templateDir)
+		if err != nil {
+			return nil, err
+		}
+		if layer == nil {
+			continue
+		}
+		manifestPath = layerPath
+		m.Name = cmp.Or(layer.Name, m.Name)
+		m.ShortName = cmp.Or(layer.ShortName, m.ShortName)
+		m.Author = cmp.Or(layer.Author, m.Author)
+		m.Description = cmp.Or(layer.Description, m.Description)
+		m.HelpURL = cmp.Or(layer.HelpURL, m.HelpURL)
+		if len(layer.Variables) > 0 {
+			m.Variables = layer.Variables
+		}
+		if layer.Hooks != nil {
+			m.Hooks = layer.Hooks
+		}
+		if i == len(templateDirs)-1 {
+			m.Version, m.Extends, m.Deleted = layer.Version, layer.Extends, layer.Deleted
+		}
+	}
+	if manifestPath == "" {
+		return Default(), nil
+	}
+
+	if len(m.Variables) == 0 {
+		m.Variables = Default().Variables
+	}
+	if m.Hooks == nil {
+		m.Hooks = DefaultHooks()
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", manifestPath, err)
+	}
+
+	return m, nil
+}
+
+// read parses the manifest in templateDir as it is written, returning nil if there is none
+func read(templateDir string) (*Manifest, string, error) {
+	manifestPath := filepath.Join(templateDir, FileName)
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, manifestPath, nil
+	}
+	if err != nil {
+		return nil, manifestPath, fmt.Errorf("failed to read manifest %s: %w", manifestPath, err)
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, manifestPath, fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+	return m, manifestPath, nil
+}
+
+// SetVersion records version in the manifest of the template at templateDir, creating
+// the manifest if the template has none. Other entries are kept as they are.
+func SetVersion(templateDir, version string) error {
+	return set(templateDir, "version", version)
+}
+
+// SetExtends records the template that the template at templateDir overlays
+func SetExtends(templateDir, ref string) error {
+	return set(templateDir, "extends", ref)
+}
+
+// SetDeleted records the paths the template at templateDir removes from the template it
+// extends, or drops the entry if there are none
+func SetDeleted(templateDir string, paths []string) error {
+	if len(paths) == 0 {
+		return set(templateDir, "deleted", nil)
+	}
+	return set(templateDir, "deleted", paths)
+}
+
+// set stores value under key in the manifest of the template at templateDir, or removes
+// the key if value is nil, keeping the other entries as they are
+func set(templateDir, key string, value any) error {
+	manifestPath := filepath.Join(templateDir, FileName)
+	entries := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) && value == nil {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read manifest %s: %w", manifestPath, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+		}
+	}
+
+	if value == nil {
+		delete(entries, key)
+	} else {
+		entries[key], _ = json.Marshal(value)
+	}
+	data, err = json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest %s: %w", manifestPath, err)
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", manifestPath, err)
+	}
+	return nil
+}
+
+// validate checks that the version, deleted paths and variable names are well formed,
+// that variable names are unique and that every validation pattern compiles
+func (m *Manifest) validate() error {
+	if m.Version != "" && !ValidVersion(m.Version) {
+		return fmt.Errorf("version '%s' must start with a letter or digit and contain only letters, digits, dots and dashes", m.Version)
+	}
+	for _, deleted := range m.Deleted {
+		if path.IsAbs(deleted) || !filepath.IsLocal(filepath.FromSlash(deleted)) {
+			return fmt.Errorf("deleted path '%s' must be relative to the template", deleted)
+		}
+	}
+	seen := make(map[string]bool, len(m.Variables))
+	for _, v := range m.Variables {
+		if !namePattern.MatchString(v.Name) {
+			return fmt.Errorf("variable name '%s' must be uppercase letters, digits and underscores", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable '%s' is declared more than once", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Validate != "" {
+			if _, err := regexp.Compile(v.Validate); err != nil {
+				return fmt.Errorf("variable '%s' has an invalid validation pattern: %w", v.Name, err)
+			}
+		}
+		if v.Derived != "" && v.Prompt != "" {
+			return fmt.Errorf("variable '%s' cannot be both prompted and derived", v.Name)
+		}
+	}
+
+	if err := validateHooks("preGenerate", m.Hooks.PreGenerate); err != nil {
+		return err
+	}
+	return validateHooks("postGenerate", m.Hooks.PostGenerate)
+}
+
+// validateHooks checks that every hook has a command, a known failure policy and a
+// working directory inside the project
+func validateHooks(stage string, hooks []Hook) error {
+	for i, h := range hooks {
+		if len(h.Command) == 0 || h.Command[0] == "" {
+			return fmt.Errorf("%s hook %d has no command", stage, i+1)
+		}
+		if h.OnFailure != "" && h.OnFailure != FailWarn && h.OnFailure != FailAbort {
+			return fmt.Errorf("%s hook '%s' has an invalid onFailure '%s' (must be %s or %s)", stage, h.Label(), h.OnFailure, FailWarn, FailAbort)
+		}
+		if filepath.IsAbs(h.Dir) {
+			return fmt.Errorf("%s hook '%s' must use a dir relative to the project", stage, h.Label())
+		}
+	}
+	return nil
+}
+
+// Label returns the hook's name, or its command if it has none
+func (h *Hook) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return strings.Join(h.Command, " ")
+}
+
+// Prompted returns the variables the user is asked for, in declaration order
+func (m *Manifest) Prompted() []Variable {
+	var vars []Variable
+	for _, v := range m.Variables {
+		if v.Derived == "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// Derived returns the variables computed from other variables, in declaration order
+func (m *Manifest) Derived() []Variable {
+	var vars []Variable
+	for _, v := range m.Variables {
+		if v.Derived != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// Label returns the text shown when prompting for the variable
+func (v *Variable) Label() string {
+	if v.Prompt != "" {
+		return v.Prompt
+	}
+	return v.Name
+}
+
+// Check validates a value against the variable's requirements
+func (v *Variable) Check(value string) error {
+	if value == "" {
+		if v.Required {
+			return fmt.Errorf("%s is required", v.Label())
+		}
+		return nil
+	}
+	if v.Validate != "" {
+		if matched, _ := regexp.MatchString(v.Validate, value); !matched {
+			return fmt.Errorf("%s '%s' does not match the pattern %s", v.Label(), value, v.Validate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		manifest     string
+		wantErr      bool
+		wantPrompted []string
+		wantDerived  []string
+	}{
+		{
+			name:         "no manifest uses defaults",
+			wantPrompted: []string{"ORGANIZATION", "PUBLISHER_NAME", "PUBLISHER_EMAIL", "PROJECT_NAME", "GITHUB", "DOMAIN"},
+		},
+		{
+			name:         "wails manifest without variables uses defaults",
+			manifest:     `{"name": "My Template", "shortname": "my-template"}`,
+			wantPrompted: []string{"ORGANIZATION", "PUBLISHER_NAME", "PUBLISHER_EMAIL", "PROJECT_NAME", "GITHUB", "DOMAIN"},
+		},
+		{
+			name: "custom variables",
+			manifest: `{"variables": [
+				{"name": "PROJECT_NAME", "prompt": "Project Name", "required": true},
+				{"name": "CHAIN", "prompt": "Chain", "default": "mainnet", "validate": "^[a-z]+$"},
+				{"name": "API_URL", "derived": "https://{{CHAIN}}.example.com"}
+			]}`,
+			wantPrompted: []string{"PROJECT_NAME", "CHAIN"},
+			wantDerived:  []string{"API_URL"},
+		},
+		{
+			name:     "invalid variable name",
+			manifest: `{"variables": [{"name": "chain"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate variable",
+			manifest: `{"variables": [{"name": "CHAIN"}, {"name": "CHAIN"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "bad validation pattern",
+			manifest: `{"variables": [{"name": "CHAIN", "validate": "[a-z"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "prompted and derived",
+			manifest: `{"variables": [{"name": "CHAIN", "prompt": "Chain", "derived": "x"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid version",
+			manifest: `{"version": "../1.0"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "deleted path outside the template",
+			manifest: `{"extends": "default", "deleted": ["../LICENSE"]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid JSON",
+			manifest: `{"variables": [}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.manifest != "" {
+				if err := os.WriteFile(filepath.Join(dir, FileName), []byte(tt.manifest), 0o644); err != nil {
+					t.Fatalf("Failed to write manifest: %v", err)
+				}
+			}
+
+			m, err := Load(dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Load() expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+
+			if got := names(m.Prompted()); !slices.Equal(got, tt.wantPrompted) {
+				t.Errorf("Prompted() = %v, want %v", got, tt.wantPrompted)
+			}
+			if got := names(m.Derived()); !slices.Equal(got, tt.wantDerived) {
+				t.Errorf("Derived() = %v, want %v", got, tt.wantDerived)
+			}
+		})
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	dir := t.TempDir()
+	if err := SetVersion(dir, "1.0"); err != nil {
+		t.Fatalf("SetVersion() unexpected error: %v", err)
+	}
+	manifest := `{"name": "My Template", "variables": [{"name": "CHAIN", "prompt": "Chain"}], "version": "1.0"}`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if err := SetVersion(dir, "1.2"); err != nil {
+		t.Fatalf("SetVersion() unexpected error: %v", err)
+	}
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if m.Version != "1.2" || m.Name != "My Template" || !slices.Equal(names(m.Prompted()), []string{"CHAIN"}) {
+		t.Errorf("Load() after SetVersion() = %+v, want version 1.2 with the other entries kept", m)
+	}
+
+	if err := SetDeleted(dir, []string{"docs"}); err != nil {
+		t.Fatalf("SetDeleted() unexpected error: %v", err)
+	}
+	if m, err := Load(dir); err != nil || !slices.Equal(m.Deleted, []string{"docs"}) {
+		t.Errorf("Load() after SetDeleted() = %+v, %v, want docs deleted", m, err)
+	}
+	if err := SetDeleted(dir, nil); err != nil {
+		t.Fatalf("SetDeleted() unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, FileName)); strings.Contains(string(data), "deleted") {
+		t.Errorf("SetDeleted() with no paths left the entry in %s", data)
+	}
+}
+
+func TestVariableCheck(t *testing.T) {
+	v := Variable{Name: "CHAIN", Prompt: "Chain", Required: true, Validate: "^[a-z]+$"}
+
+	if err := v.Check("mainnet"); err != nil {
+		t.Errorf("Check(mainnet) unexpected error: %v", err)
+	}
+	if err := v.Check(""); err == nil || err.Error() != "Chain is required" {
+		t.Errorf("Check(\"\") = %v, want required error", err)
+	}
+	if err := v.Check("Main Net"); err == nil {
+		t.Errorf("Check(Main Net) expected pattern error")
+	}
+
+	optional := Variable{Name: "API_URL"}
+	if err := optional.Check(""); err != nil {
+		t.Errorf("Check(\"\") on optional variable unexpected error: %v", err)
+	}
+}
+
+func names(vars []Variable) []string {
+	var result []string
+	for _, v := range vars {
+		result = append(result, v.Name)
+	}
+	return result
+}
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  bool
+		wantPre  int
+		wantPost int
+	}{
+		{
+			name:     "no hooks entry uses default hooks",
+			manifest: `{"name": "My Template"}`,
+			wantPost: 2,
+		},
+		{
+			name:     "empty hooks entry runs nothing",
+			manifest: `{"hooks": {}}`,
+		},
+		{
+			name: "custom hooks",
+			manifest: `{"hooks": {
+				"preGenerate": [{"command": ["go", "version"], "onFailure": "abort"}],
+				"postGenerate": [{"name": "tidy", "command": ["go", "mod", "tidy"], "env": {"GOFLAGS": "-mod=mod"}}]
+			}}`,
+			wantPre:  1,
+			wantPost: 1,
+		},
+		{
+			name:     "hook without command",
+			manifest: `{"hooks": {"postGenerate": [{"name": "empty"}]}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid failure policy",
+			manifest: `{"hooks": {"postGenerate": [{"command": ["make"], "onFailure": "ignore"}]}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "absolute dir",
+			manifest: `{"hooks": {"postGenerate": [{"command": ["make"], "dir": "/tmp"}]}}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, FileName), []byte(tt.manifest), 0o644); err != nil {
+				t.Fatalf("Failed to write manifest: %v", err)
+			}
+
+			m, err := Load(dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Load() expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if len(m.Hooks.PreGenerate) != tt.wantPre || len(m.Hooks.PostGenerate) != tt.wantPost {
+				t.Errorf("Load() hooks = %d pre, %d post, want %d pre, %d post",
+					len(m.Hooks.PreGenerate), len(m.Hooks.PostGenerate), tt.wantPre, tt.wantPost)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package merge
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written around the two sides of a conflicting region
+const (
+	MarkerOurs   = "<<<<<<< project"
+	MarkerSplit  = "======="
+	MarkerTheirs = ">>>>>>> template"
+)
+
+// maxTableSize bounds the lines-by-lines table used to align regions that have no
+// unique lines in common. Larger regions are treated as entirely changed.
+const maxTableSize = 1 << 22
+
+// Merge combines the changes made to base in ours and in theirs, line by line. Where
+// both sides changed the same lines differently the region is kept with conflict
+// markers around both versions. It returns the merged text and the number of conflicts.
+func Merge(base, ours, theirs string) (string, int) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	toOurs := align(b, o)
+	toTheirs := align(b, t)
+
+	var sb strings.Builder
+	conflicts := 0
+	bi, oi, ti := 0, 0, 0
+	for bi < len(b) || oi < len(o) || ti < len(t) {
+		// Lines unchanged on both sides are copied as they are
+		if bi < len(b) && toOurs[bi] == oi && toTheirs[bi] == ti {
+			sb.WriteString(b[bi])
+			bi, oi, ti = bi+1, oi+1, ti+1
+			continue
+		}
+
+		// Otherwise the changed region runs to the next line both sides kept
+		next := bi
+		for next < len(b) && (toOurs[next] < 0 || toTheirs[next] < 0) {
+			next++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if next < len(b) {
+			oEnd, tEnd = toOurs[next], toTheirs[next]
+		}
+
+		baseChunk, oursChunk, theirsChunk := b[bi:next], o[oi:oEnd], t[ti:tEnd]
+		switch {
+		case slices.Equal(oursChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			writeLines(&sb, theirsChunk)
+		case slices.Equal(theirsChunk, baseChunk):
+			writeLines(&sb, oursChunk)
+		default:
+			conflicts++
+			sb.WriteString(MarkerOurs + "\n")
+			writeTerminated(&sb, oursChunk)
+			sb.WriteString(MarkerSplit + "\n")
+			writeTerminated(&sb, theirsChunk)
+			sb.WriteString(MarkerTheirs + "\n")
+		}
+		bi, oi, ti = next, oEnd, tEnd
+	}
+	return sb.String(), conflicts
+}
+
+// splitLines splits text into lines, each keeping its line ending
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// writeLines writes lines unchanged
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+	}
+}
+
+// writeTerminated writes lines, ending the last one with a newline so a marker can follow
+func writeTerminated(sb *strings.Builder, lines []string) {
+	writeLines(sb, lines)
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// align returns, for each line of a, the index of the line of b it is matched with,
+// or -1. Matched indexes always increase, so the pairs form a common subsequence.
+func align(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	alignRange(a, b, 0, len(a), 0, len(b), match)
+	return match
+}
+
+// alignRange matches a[alo:ahi] against b[blo:bhi]. Common leading and trailing lines
+// are matched first; the rest is anchored on lines that occur exactly once on each side
+// (patience diff), and regions without such lines fall back to a longest common
+// subsequence table when it is small enough.
+func alignRange(a, b []string, alo, ahi, blo, bhi int, match []int) {
+	for alo < ahi && blo < bhi && a[alo] == b[blo] {
+		match[alo] = blo
+		alo, blo = alo+1, blo+1
+	}
+	for alo < ahi && blo < bhi && a[ahi-1] == b[bhi-1] {
+		match[ahi-1] = bhi - 1
+		ahi, bhi = ahi-1, bhi-1
+	}
+	if alo == ahi || blo == bhi {
+		return
+	}
+
+	if anchors := uniqueAnchors(a, b, alo, ahi, blo, bhi); len(anchors) > 0 {
+		for _, anchor := range anchors {
+			alignRange(a, b, alo, anchor[0], blo, anchor[1], match)
+			match[anchor[0]] = anchor[1]
+			alo, blo = anchor[0]+1, anchor[1]+1
+		}
+		alignRange(a, b, alo, ahi, blo, bhi, match)
+		return
+	}
+
+	if (ahi-alo)*(bhi-blo) <= maxTableSize {
+		lcsTable(a, b, alo, ahi, blo, bhi, match)
+	}
+}
+
+// uniqueAnchors returns the pairs of lines that occur exactly once in both ranges,
+// reduced to the longest run whose positions increase on both sides
+func uniqueAnchors(a, b []string, alo, ahi, blo, bhi int) [][2]int {
+	type counts struct{ inA, inB, posA, posB int }
+	seen := make(map[string]*counts)
+	for i := alo; i < ahi; i++ {
+		c := seen[a[i]]
+		if c == nil {
+			c = &counts{}
+			seen[a[i]] = c
+		}
+		c.inA++
+		c.posA = i
+	}
+	for j := blo; j < bhi; j++ {
+		if c := seen[b[j]]; c != nil {
+			c.inB++
+			c.posB = j
+		}
+	}
+
+	var pairs [][2]int
+	for i := alo; i < ahi; i++ {
+		if c := seen[a[i]]; c.inA == 1 && c.inB == 1 {
+			pairs = append(pairs, [2]int{c.posA, c.posB})
+		}
+	}
+	return longestIncreasing(pairs)
+}
+
+// longestIncreasing returns the longest subsequence of pairs (already ordered by their
+// first element) whose second elements increase
+func longestIncreasing(pairs [][2]int) [][2]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+	var tails []int // index into pairs of the smallest tail of each run length
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		n, _ := slices.BinarySearchFunc(tails, p[1], func(k, target int) int {
+			return pairs[k][1] - target
+		})
+		if n > 0 {
+			prev[i] = tails[n-1]
+		} else {
+			prev[i] = -1
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+
+	run := make([][2]int, len(tails))
+	for i, k := len(tails)-1, tails[len(tails)-1]; i >= 0; i, k = i-1, prev[k] {
+		run[i] = pairs[k]
+	}
+	return run
+}
+
+// lcsTable matches a[alo:ahi] against b[blo:bhi] with a longest common subsequence table
+func lcsTable(a, b []string, alo, ahi, blo, bhi int, match []int) {
+	n, m := ahi-alo, bhi-blo
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[alo+i] == b[blo+j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[alo+i] == b[blo+j]:
+			match[alo+i] = blo + j
+			i, j = i+1, j+1
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+}
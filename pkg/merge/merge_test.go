@@ -0,0 +1,102 @@
+package merge
+
+import (
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nB\nc\nd\n",
+			want:   "a\nB\nc\nd\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "x\na\nb\n",
+			theirs: "a\nb\nc\n",
+			want:   "x\na\nb\n",
+		},
+		{
+			name:   "separate changes",
+			base:   "one\ntwo\nthree\nfour\nfive\n",
+			ours:   "one\nTWO\nthree\nfour\nfive\n",
+			theirs: "one\ntwo\nthree\nfour\nFIVE\nsix\n",
+			want:   "one\nTWO\nthree\nfour\nFIVE\nsix\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nX\nc\n",
+			theirs: "a\nX\nc\n",
+			want:   "a\nX\nc\n",
+		},
+		{
+			name:          "conflicting changes",
+			base:          "a\nb\nc\n",
+			ours:          "a\nours\nc\n",
+			theirs:        "a\ntheirs\nc\n",
+			want:          "a\n<<<<<<< project\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "conflict without trailing newline",
+			base:          "a\nb",
+			ours:          "a\nours",
+			theirs:        "a\ntheirs",
+			want:          "a\n<<<<<<< project\nours\n=======\ntheirs\n>>>>>>> template\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "added on both sides without a base",
+			base:          "",
+			ours:          "x\n",
+			theirs:        "y\n",
+			want:          "<<<<<<< project\nx\n=======\ny\n>>>>>>> template\n",
+			wantConflicts: 1,
+		},
+		{
+			name:   "deleted by theirs",
+			base:   "a\nb\nc\nd\n",
+			ours:   "a\nb\nc\nd\nours\n",
+			theirs: "a\nd\n",
+			want:   "a\nd\nours\n",
+		},
+		{
+			name:   "repeated lines",
+			base:   "}\n}\nfunc a() {\n}\n}\n",
+			ours:   "}\n}\nfunc a() {\n\treturn\n}\n}\n",
+			theirs: "}\n}\n}\nfunc a() {\n}\n}\n",
+			want:   "}\n}\n}\nfunc a() {\n\treturn\n}\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge(tt.base, tt.ours, tt.theirs)
+			if got != tt.want {
+				t.Errorf("Merge() = %q, want %q", got, tt.want)
+			}
+			if conflicts != tt.wantConflicts {
+				t.Errorf("Merge() conflicts = %d, want %d", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
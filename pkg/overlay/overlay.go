@@ -0,0 +1,104 @@
+package overlay
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Layer is one directory of a layered template and the slash-separated paths it removes
+// from the layers below it
+type Layer struct {
+	Dir     string
+	Deleted []string
+}
+
+// entry is a file or directory of the merged tree and where it comes from
+type entry struct {
+	path string
+	info fs.FileInfo
+}
+
+// Walk calls fn for every file and directory of the tree made by stacking layers, base
+// first: a layer's files replace those at the same paths below it and its deleted paths,
+// with everything under them, are taken out before its own files are added. Entries are
+// visited in the order filepath.Walk uses, root first, with path naming the file on disk
+// and relPath its place in the merged tree. Returning filepath.SkipDir from fn skips a
+// directory, or the rest of the directory holding a file; filepath.SkipAll stops the walk.
+func Walk(layers []Layer, fn func(path, relPath string, info fs.FileInfo) error) error {
+	entries := make(map[string]entry)
+	for _, layer := range layers {
+		for _, deleted := range layer.Deleted {
+			removeTree(entries, path.Clean(deleted))
+		}
+		err := filepath.Walk(layer.Dir, func(filePath string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, _ := filepath.Rel(layer.Dir, filePath)
+			relPath = filepath.ToSlash(relPath)
+			if old, ok := entries[relPath]; ok && old.info.IsDir() != info.IsDir() {
+				// A file replacing a directory takes everything under it away, and the
+				// other way around
+				removeTree(entries, relPath)
+			}
+			entries[relPath] = entry{filePath, info}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	relPaths := make([]string, 0, len(entries))
+	for relPath := range entries {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Slice(relPaths, func(i, j int) bool { return less(relPaths[i], relPaths[j]) })
+
+	skip := ""
+	for _, relPath := range relPaths {
+		if skip != "" && strings.HasPrefix(relPath, skip) {
+			continue
+		}
+		e := entries[relPath]
+		err := fn(e.path, filepath.FromSlash(relPath), e.info)
+		switch {
+		case err == filepath.SkipAll:
+			return nil
+		case err == filepath.SkipDir && relPath == ".":
+			return nil
+		case err == filepath.SkipDir && e.info.IsDir():
+			skip = relPath + "/"
+		case err == filepath.SkipDir:
+			if path.Dir(relPath) == "." {
+				return nil
+			}
+			skip = path.Dir(relPath) + "/"
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTree removes relPath and everything under it from entries
+func removeTree(entries map[string]entry, relPath string) {
+	for other := range entries {
+		if other == relPath || strings.HasPrefix(other, relPath+"/") {
+			delete(entries, other)
+		}
+	}
+}
+
+// less orders slash-separated paths as filepath.Walk visits them: the root first, each
+// directory before its contents and the entries of a directory by name
+func less(a, b string) bool {
+	if a == "." || b == "." {
+		return a == "." && b != "."
+	}
+	return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/")) < 0
+}
@@ -0,0 +1,77 @@
+package overlay
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestWalk(t *testing.T) {
+	base := t.TempDir()
+	top := t.TempDir()
+	testutil.WriteFiles(t, base, map[string]string{
+		"README.md":      "base readme",
+		"main.go":        "base main",
+		"docs/guide.md":  "base guide",
+		"old/a.txt":      "old",
+		"old/keep/b.txt": "old",
+		"tool":           "a file below",
+	})
+	testutil.WriteFiles(t, top, map[string]string{
+		"main.go":     "top main",
+		"extra.txt":   "top extra",
+		"old/new.txt": "re-added",
+		"tool/run.sh": "a directory on top",
+	})
+	layers := []Layer{{Dir: base}, {Dir: top, Deleted: []string{"README.md", "old"}}}
+
+	walk := func(skip string) (map[string]string, []string) {
+		got := make(map[string]string)
+		var order []string
+		err := Walk(layers, func(path, relPath string, info fs.FileInfo) error {
+			relPath = filepath.ToSlash(relPath)
+			order = append(order, relPath)
+			if relPath == skip {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() {
+				data, _ := os.ReadFile(path)
+				got[relPath] = string(data)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() unexpected error: %v", err)
+		}
+		return got, order
+	}
+
+	got, order := walk("")
+	want := map[string]string{
+		"main.go":       "top main",
+		"extra.txt":     "top extra",
+		"docs/guide.md": "base guide",
+		"old/new.txt":   "re-added",
+		"tool/run.sh":   "a directory on top",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Walk() visited files %v, want %v", got, want)
+	}
+	for relPath, content := range want {
+		if got[relPath] != content {
+			t.Errorf("Walk() %s = %q, want %q", relPath, got[relPath], content)
+		}
+	}
+	wantOrder := []string{".", "docs", "docs/guide.md", "extra.txt", "main.go", "old", "old/new.txt", "tool", "tool/run.sh"}
+	if !slices.Equal(order, wantOrder) {
+		t.Errorf("Walk() order = %v, want %v", order, wantOrder)
+	}
+
+	if got, _ := walk("docs"); got["docs/guide.md"] != "" || got["main.go"] == "" {
+		t.Errorf("Walk() after SkipDir on docs = %v, want docs skipped and the rest visited", got)
+	}
+}
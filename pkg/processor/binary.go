@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// binaryExtensions are always copied byte-for-byte regardless of their content
+var binaryExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".bmp", ".ico", ".icns", ".webp", ".tiff",
+	".woff", ".woff2", ".ttf", ".otf", ".eot",
+	".zip", ".gz", ".tgz", ".tar", ".bz2", ".xz", ".7z",
+	".pdf", ".mp3", ".mp4", ".mov", ".wav", ".webm",
+	".exe", ".dll", ".so", ".dylib", ".a", ".o", ".wasm", ".syso",
+}
+
+// sniffLen is how much of a file is inspected for NUL bytes
+const sniffLen = 8000
+
+// IsBinary reports whether a file must be copied byte-for-byte instead of having
+// template variables applied. Known binary extensions always are; otherwise a file is
+// binary if it contains a NUL byte near the start or is not valid UTF-8.
+func IsBinary(path string, data []byte) bool {
+	if slices.Contains(binaryExtensions, strings.ToLower(filepath.Ext(path))) {
+		return true
+	}
+
+	head := data
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	if bytes.IndexByte(head, 0) != -1 {
+		return true
+	}
+
+	return !utf8.Valid(data)
+}
+
+// FileReport records which files were processed as text and which were copied as binary
+type FileReport struct {
+	Text   []string
+	Binary []string
+}
+
+// Add records a processed file
+func (r *FileReport) Add(relPath string, binary bool) {
+	if binary {
+		r.Binary = append(r.Binary, relPath)
+	} else {
+		r.Text = append(r.Text, relPath)
+	}
+}
+
+// Print displays a summary of the report, listing every binary file
+func (r *FileReport) Print() {
+	fmt.Printf("Processed %d text files with template variables\n", len(r.Text))
+	if len(r.Binary) == 0 {
+		return
+	}
+	fmt.Printf("Copied %d binary files byte-for-byte:\n", len(r.Binary))
+	for _, relPath := range slices.Sorted(slices.Values(r.Binary)) {
+		fmt.Printf("    %s\n", relPath)
+	}
+}
@@ -0,0 +1,37 @@
+package processor
+
+// CaseVariants holds a name written in each supported case style
+type CaseVariants struct {
+	Pascal    string
+	Camel     string
+	Snake     string
+	Kebab     string
+	Screaming string
+	Title     string
+}
+
+// NewCaseVariants returns name in every case style, splitting it into words at spaces,
+// punctuation and changes of case (so "my-app" gives MyApp, myApp, my_app, my-app,
+// MY_APP and My App)
+func NewCaseVariants(name string) CaseVariants {
+	return CaseVariants{
+		Pascal:    toPascal(name),
+		Camel:     toCamel(name),
+		Snake:     toSnake(name),
+		Kebab:     toKebab(name),
+		Screaming: toScreamingSnake(name),
+		Title:     toTitle(name),
+	}
+}
+
+// tokens returns the variants keyed by token name, each name starting with prefix
+func (c CaseVariants) tokens(prefix string) map[string]string {
+	return map[string]string{
+		prefix + "PASCAL":    c.Pascal,
+		prefix + "CAMEL":     c.Camel,
+		prefix + "SNAKE":     c.Snake,
+		prefix + "KEBAB":     c.Kebab,
+		prefix + "SCREAMING": c.Screaming,
+		prefix + "TITLE":     c.Title,
+	}
+}
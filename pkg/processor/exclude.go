@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/TrueBlocks/create-local-app/pkg/ignore"
+)
+
+// IgnoreFile holds gitignore-style rules that decide what --create leaves out of a
+// template. Its rules take precedence over the project's .gitignore files and the defaults.
+const IgnoreFile = ".create-local-app-ignore"
+
+// DefaultIgnoreRules are applied before the project's own rules, which may override
+// them with ! patterns
+var DefaultIgnoreRules = []string{
+	".git",
+	"node_modules",
+	"dist",
+	".create-local-app.json",
+	".create-local-app.base.tar.gz",
+	".create-local-app.lock",
+	// staging directories left behind by a run that was killed
+	".create-local-app-staging-*/",
+	"*.rej",
+	".wails-template.json",
+	".DS_Store",
+	".env",
+	"shit",
+	"Thumbs.db",
+	// build output, except the plists Wails needs
+	"**/build/**",
+	"!**/build/**/",
+	"!**/build/**/Info.plist",
+	"!**/build/**/Info.dev.plist",
+	// ai working files, except its documentation
+	"**/ai/**",
+	"!**/ai/**/",
+	"!**/ai/**/.gitignore",
+	"!**/ai/**/README.md",
+	"!**/ai/**/Invoker.md",
+	"!**/ai/**/Rules.md",
+	// generated output folders, keeping their .gitignore
+	"**/output/**",
+	"!**/output/**/",
+	"!**/output/**/.gitignore",
+	// rendered mdbook
+	"**/book/book/",
+}
+
+// Excluder decides which files and folders of a project are left out of a template
+type Excluder struct {
+	root    string
+	matcher *ignore.Matcher
+}
+
+// NewExcluder builds the exclusion rules for the project at root: DefaultIgnoreRules,
+// then every .gitignore in the project, then the project's IgnoreFile
+func NewExcluder(root string) (*Excluder, error) {
+	matcher := ignore.New(root, ".gitignore")
+	if err := matcher.AddDefaults(DefaultIgnoreRules); err != nil {
+		return nil, err
+	}
+	if err := matcher.AddOverrideFile(IgnoreFile); err != nil {
+		return nil, err
+	}
+	return &Excluder{root: root, matcher: matcher}, nil
+}
+
+// IsExcluded determines if a file or directory should be excluded from processing. For
+// an excluded directory it also returns filepath.SkipDir so a walk can skip its contents.
+func (e *Excluder) IsExcluded(path string, info fs.FileInfo) (bool, error) {
+	relPath, err := filepath.Rel(e.root, path)
+	if err != nil {
+		return true, fmt.Errorf("failed to get relative path for %s: %w", path, err)
+	}
+
+	excluded, err := e.matcher.Match(relPath, info.IsDir())
+	if err != nil {
+		return true, err
+	}
+	if excluded && info.IsDir() {
+		return true, filepath.SkipDir
+	}
+	return excluded, nil
+}
@@ -0,0 +1,190 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// templateFuncs returns the function library available inside {% %} template actions
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"title":     toTitle,
+		"kebab":     toKebab,
+		"snake":     toSnake,
+		"screaming": toScreamingSnake,
+		"camel":     toCamel,
+		"pascal":    toPascal,
+		"plural":    toPlural,
+		"singular":  toSingular,
+		"trim":      strings.TrimSpace,
+		"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":      joinValues,
+		"default":   defaultValue,
+		"date":      func(layout string) string { return time.Now().Format(layout) },
+		"year":      func() int { return time.Now().Year() },
+	}
+}
+
+// splitWords breaks a value into words at separators, lower-to-upper transitions
+// and the end of acronyms, so "my-app", "myApp" and "MyAPIServer" all split sensibly
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(current) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// capitalize uppercases the first rune of a word and lowercases the rest
+func capitalize(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func toKebab(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "-"))
+}
+
+func toSnake(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "_"))
+}
+
+func toScreamingSnake(s string) string {
+	return strings.ToUpper(strings.Join(splitWords(s), "_"))
+}
+
+func toPascal(s string) string {
+	var sb strings.Builder
+	for _, word := range splitWords(s) {
+		sb.WriteString(capitalize(word))
+	}
+	return sb.String()
+}
+
+func toCamel(s string) string {
+	words := splitWords(s)
+	if len(words) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(words[0]))
+	for _, word := range words[1:] {
+		sb.WriteString(capitalize(word))
+	}
+	return sb.String()
+}
+
+func toTitle(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = capitalize(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// toPlural applies simple English pluralization rules to s
+func toPlural(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(lower, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + matchCase(s, "ies")
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + matchCase(s, "es")
+	default:
+		return s + matchCase(s, "s")
+	}
+}
+
+// toSingular reverses the rules applied by toPlural
+func toSingular(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + matchCase(s, "y")
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"):
+		return s
+	case strings.HasSuffix(lower, "s") && len(s) > 1:
+		return s[:len(s)-1]
+	default:
+		return s
+	}
+}
+
+// matchCase returns suffix uppercased if s is entirely uppercase
+func matchCase(s, suffix string) string {
+	if s == strings.ToUpper(s) && s != strings.ToLower(s) {
+		return strings.ToUpper(suffix)
+	}
+	return suffix
+}
+
+// joinValues joins a list of values with sep, accepting either strings or arbitrary values
+func joinValues(sep string, values any) (string, error) {
+	switch v := values.(type) {
+	case []string:
+		return strings.Join(v, sep), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, sep), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("join: unsupported list type %T", values)
+	}
+}
+
+// defaultValue returns def if value is empty, otherwise value
+func defaultValue(def string, value any) string {
+	if value == nil {
+		return def
+	}
+	if s := fmt.Sprint(value); s != "" {
+		return s
+	}
+	return def
+}
@@ -1,70 +1,18 @@
 package processor
 
 import (
-	"io/fs"
+	"fmt"
+	"maps"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
+	"text/template"
 
 	"github.com/TrueBlocks/create-local-app/pkg/config"
 	"github.com/TrueBlocks/trueblocks-chifra/v6/pkg/file"
 )
 
-// IsExcluded determines if a file or directory should be excluded from processing
-func IsExcluded(path string, info fs.FileInfo) (bool, error) {
-	_ = info // linter
-	baseName := filepath.Base(path)
-	folderName := filepath.Base(filepath.Dir(path))
-
-	folderSkips := []string{".git", "node_modules", "dist"}
-	if slices.Contains(folderSkips, folderName) {
-		return true, filepath.SkipDir
-	}
-
-	// Also check if the current file/folder name should be skipped
-	if slices.Contains(folderSkips, baseName) {
-		if info.IsDir() {
-			return true, filepath.SkipDir
-		} else {
-			return true, nil
-		}
-	}
-
-	fileSkips := []string{
-		".create-local-app.json",
-		".DS_Store",
-		".env",
-		"shit",
-		"Thumbs.db",
-	}
-	if slices.Contains(fileSkips, baseName) {
-		return true, nil
-	}
-
-	keeps := []string{"Info.plist", "Info.dev.plist"}
-	if strings.Contains(path, "/build/") && !slices.Contains(keeps, baseName) {
-		return true, nil
-	}
-
-	if strings.Contains(path, "/ai") {
-		keeps := []string{".gitignore", "README.md", "Invoker.md", "Rules.md"}
-		if slices.Contains(keeps, baseName) {
-			return false, nil
-		}
-		return baseName != "ai", nil
-	}
-
-	if strings.Contains(path, "/output") {
-		return baseName != ".gitignore", nil
-	}
-
-	if strings.Contains(path, "/book/book") && baseName != "book.toml" {
-		return true, nil
-	}
-
-	return false, nil
-}
-
 // ShouldPreserve determines if an existing file should be preserved and not replaced
 func ShouldPreserve(filePath string, cfg *config.Config) bool {
 	if cfg == nil || len(cfg.PreserveFiles) == 0 {
@@ -99,110 +47,218 @@ type TemplateVars struct {
 	Github         string
 	Domain         string
 	Chifra         string
+	ProjectCases   CaseVariants
+	OrgCases       CaseVariants
+	Features       []string
+	Extra          map[string]string
+	Preserves      []string
+}
+
+// Tokens returns the value of every legacy {{TOKEN}} placeholder keyed by token name.
+// The same map is the data passed to {% %} template actions, so {% .PROJECT_NAME | pascal %}
+// and {{PROJECT_NAME}} refer to the same value. Variables declared by the template's
+// manifest (Extra) are included and take precedence over the built-in tokens. Derived
+// tokens are computed last, so a template that declares MODULE moves APP and PACKAGES with it.
+func (vars *TemplateVars) Tokens() map[string]string {
+	tokens := map[string]string{
+		"SDK":             "github.com/TrueBlocks/trueblocks-sdk/v5",
+		"DALLE":           "github.com/TrueBlocks/trueblocks-dalle/v2",
+		"PROJECT_NAME":    vars.ProjectName,
+		"PROJECT_PROPER":  vars.ProjectProper,
+		"PUBLISHER_NAME":  vars.PublisherName,
+		"PUBLISHER_EMAIL": vars.PublisherEmail,
+		"ORGANIZATION":    vars.Organization,
+		"ORG_NAME":        vars.OrgName,
+		"ORG_LOWER":       vars.OrgLower,
+		"SLUG":            vars.Slug,
+		"GITHUB":          vars.Github,
+		"DOMAIN":          vars.Domain,
+		"CHIFRA":          vars.Chifra,
+	}
+	maps.Copy(tokens, vars.ProjectCases.tokens("PROJECT_"))
+	maps.Copy(tokens, vars.OrgCases.tokens("ORG_"))
+	for name, value := range vars.Extra {
+		tokens[name] = value
+	}
+	for _, d := range derivedTokens {
+		if _, ok := tokens[d.name]; !ok {
+			tokens[d.name] = d.derive(tokens)
+		}
+	}
+	return tokens
+}
+
+// derivedTokens are computed in order from the other tokens unless the template
+// declares a variable of the same name
+var derivedTokens = []struct {
+	name   string
+	derive func(tokens map[string]string) string
+}{
+	{"MODULE", func(t map[string]string) string { return ModulePath(t["GITHUB"], t["ORG_NAME"], t["SLUG"]) }},
+	{"APP", func(t map[string]string) string { return t["MODULE"] + "/app" }},
+	{"PACKAGES", func(t map[string]string) string { return t["MODULE"] + "/pkg" }},
+	{"SAVEPKG", func(t map[string]string) string { return t["PACKAGES"] }},
+}
+
+// ModulePath returns the Go module path of a project. A Github value that names a
+// repository, as host/owner/repo or a URL, is the module path as it stands; one that names
+// only an owner, as host/owner or a bare owner, gets the slug as its repository. If Github
+// names no owner, the organization name on github.com is used instead.
+func ModulePath(github, orgName, slug string) string {
+	github = strings.TrimSpace(github)
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git@"} {
+		github = strings.TrimPrefix(github, prefix)
+	}
+	github = strings.TrimSuffix(strings.TrimSuffix(github, "/"), ".git")
+	github = strings.Replace(github, ":", "/", 1) // git@github.com:owner/repo
+
+	parts := strings.Split(github, "/")
+	if !strings.Contains(parts[0], ".") {
+		parts = append([]string{"github.com"}, parts...)
+	}
+	switch {
+	case len(parts) > 2 && parts[2] != "":
+		return strings.Join(parts, "/")
+	case len(parts) > 1 && parts[1] != "":
+		return parts[0] + "/" + parts[1] + "/" + slug
+	default:
+		return parts[0] + "/" + orgName + "/" + slug
+	}
+}
+
+// HasFeature reports whether the named feature was selected for this project
+func (vars *TemplateVars) HasFeature(name string) bool {
+	return slices.Contains(vars.Features, name)
 }
 
-// ApplyTemplateVars applies template variable replacements to content
-func ApplyTemplateVars(content string, vars *TemplateVars) string {
-	content = strings.ReplaceAll(content, "{{SDK}}", "github.com/TrueBlocks/trueblocks-sdk/v5")
-	content = strings.ReplaceAll(content, "{{PACKAGES}}", "{{SAVEPKG}}")
-	content = strings.ReplaceAll(content, "{{DALLE}}", "github.com/TrueBlocks/trueblocks-dalle/v2")
-	content = strings.ReplaceAll(content, "{{APP}}", "github.com/TrueBlocks/"+vars.Slug+"/app")
-	content = strings.ReplaceAll(content, "{{PROJECT_NAME}}", vars.ProjectName)
-	content = strings.ReplaceAll(content, "{{PROJECT_PROPER}}", vars.ProjectProper)
-	content = strings.ReplaceAll(content, "{{PUBLISHER_NAME}}", vars.PublisherName)
-	content = strings.ReplaceAll(content, "{{PUBLISHER_EMAIL}}", vars.PublisherEmail)
-	content = strings.ReplaceAll(content, "{{ORGANIZATION}}", vars.Organization)
-	content = strings.ReplaceAll(content, "{{ORG_NAME}}", vars.OrgName)
-	content = strings.ReplaceAll(content, "{{ORG_LOWER}}", vars.OrgLower)
-	content = strings.ReplaceAll(content, "{{SLUG}}", vars.Slug)
-	content = strings.ReplaceAll(content, "{{GITHUB}}", vars.Github)
-	content = strings.ReplaceAll(content, "{{DOMAIN}}", vars.Domain)
-	content = strings.ReplaceAll(content, "{{CHIFRA}}", vars.Chifra)
-	content = strings.ReplaceAll(content, "{{SAVEPKG}}", "github.com/TrueBlocks/"+vars.Slug+"/pkg")
-	return content
+// templateData returns the data passed to {% %} template actions: every token by name
+// plus FEATURES, the list of selected features
+func (vars *TemplateVars) templateData() map[string]any {
+	tokens := vars.Tokens()
+	data := make(map[string]any, len(tokens)+1)
+	for k, v := range tokens {
+		data[k] = v
+	}
+	features := vars.Features
+	if features == nil {
+		features = []string{}
+	}
+	data["FEATURES"] = features
+	return data
 }
 
-// ReverseTemplateVars reverses template variable replacements (for create mode)
-func ReverseTemplateVars(content string, vars *TemplateVars) string {
-	preserves := []struct {
-		old string
-		new string
-	}{
-		{"Block explorer", "{{101}}"},
-		{"Block Explorer", "{{102}}"},
-		{"class Explorer", "{{103}}"},
-		{"dalleDressStore", "{{104}}"},
-		{"Explorers", "{{105}}"},
-		{"explorers: Explorer[];", "{{106}}"},
-		{"ExportApprovals", "{{107}}"},
-		{"ExportsOpenApprovals", "{{108}}"},
-		{"getOpenapprovalsFields", "{{109}}"},
-		{"getOpenApprovalsStore", "{{110}}"},
-		{"json:\"dalledress\"", "{{111}}"},
-		{"local block explorer", "{{112}}"},
-		{"Local Explorer", "{{113}}"},
-		{"localExplorer", "{{114}}"},
-		{"LocalExplorer", "{{115}}"},
-		{"new Explorer", "{{116}}"},
-		{"Open Approvals", "{{117}}"},
-		{"openapprovals", "{{118}}"},
-		{"OpenApprovals", "{{119}}"},
-		{"OPENAPPROVALS", "{{120}}"},
-		{"openapprovalsFacet", "{{121}}"},
-		{"openapprovalsStore", "{{122}}"},
-		{"openapprovalsStoreMu", "{{123}}"},
-		{"pageData?.dalledress", "{{124}}"},
-		{"pageData.dalledress", "{{125}}"},
-		{"remote block explorer", "{{126}}"},
-		{"Remote Explorer", "{{127}}"},
-		{"remoteExplorer", "{{128}}"},
-		{"RemoteExplorer", "{{129}}"},
-		{"SortOpenApprovals", "{{130}}"},
-		{"this.explorers = this.convertValues(source[\"explorers\"], Explorer", "{{131}}"},
-		{"TokensApprovals", "{{132}}"},
-		{"dalledressStore", "{{133}}"},
-		{"dalledressStoreMu", "{{134}}"},
-		{"dresses-dalledress", "{{135}}"},
-		{"\"dalledress\":", "{{136}}"},
-		{"getDalledressFields", "{{137}}"},
-		{"Store:         \"dalledress\"", "{{138}}"},
-		{"this.dalledress = this.convertValues(source[\"dalledress\"], model.DalleDress);", "{{139}}"},
-		{"dalledress: model.DalleDress[];", "{{140}}"},
-		{"dalledress:", "{{141}}"},
-	}
-	for _, pr := range preserves {
-		content = strings.ReplaceAll(content, pr.old, pr.new)
-	}
-
-	content = strings.ReplaceAll(content, "github.com/TrueBlocks/trueblocks-sdk/v5", "{{SDK}}")
-	content = strings.ReplaceAll(content, "github.com/TrueBlocks/trueblocks-dalle/v2", "{{DALLE}}")
-	content = strings.ReplaceAll(content, "github.com/TrueBlocks/"+vars.Slug+"/pkg", "{{PACKAGES}}")
-	content = strings.ReplaceAll(content, "github.com/TrueBlocks/"+vars.Slug+"/app", "{{APP}}")
-	content = strings.ReplaceAll(content, vars.Chifra, "{{CHIFRA}}")
-	if vars.Domain != "" {
-		content = strings.ReplaceAll(content, vars.Domain, "{{DOMAIN}}")
-	}
-	if vars.Github != "" {
-		content = strings.ReplaceAll(content, vars.Github, "{{GITHUB}}")
-	}
-	if vars.Slug != "" {
-		content = strings.ReplaceAll(content, vars.Slug, "{{SLUG}}")
-	}
-	if vars.OrgName != "" {
-		content = strings.ReplaceAll(content, vars.OrgName, "{{ORG_NAME}}")
-		content = strings.ReplaceAll(content, vars.OrgLower, "{{ORG_LOWER}}")
-	}
-	if vars.Organization != "" {
-		content = strings.ReplaceAll(content, vars.Organization, "{{ORGANIZATION}}")
-	}
-	if vars.ProjectName != "" {
-		content = strings.ReplaceAll(content, vars.ProjectName, "{{PROJECT_NAME}}")
-		content = strings.ReplaceAll(content, vars.ProjectProper, "{{PROJECT_PROPER}}")
-	}
-
-	for _, pr := range preserves {
-		content = strings.ReplaceAll(content, pr.new, pr.old)
-	}
-
-	return content
+// Template actions use {% %} delimiters so that the {{ }} used by Go templates,
+// JSX and GitHub workflows inside template files pass through untouched
+const (
+	leftDelim  = "{%"
+	rightDelim = "%}"
+)
+
+// tokenPattern matches a legacy {{TOKEN}} placeholder
+var tokenPattern = regexp.MustCompile(`\{\{([A-Z][A-Z0-9_]*)\}\}`)
+
+// actionPattern matches a {% %} template action, which may span lines
+var actionPattern = regexp.MustCompile(`\{%(?:[^%]|%[^}])*%\}`)
+
+// fieldPattern matches a variable referenced as .TOKEN inside a template action
+var fieldPattern = regexp.MustCompile(`\.([A-Z][A-Z0-9_]*)\b`)
+
+// standaloneBlockPattern matches a line holding nothing but a block action such as
+// {% if feature "dalle" %} or {% end %}
+var standaloneBlockPattern = regexp.MustCompile(`(?m)^[ \t]*(\{%-?\s*(?:if|else|end|range|with|break|continue|/\*)(?:[^%\n]|%[^}\n])*%\})[ \t]*\r?\n`)
+
+// ApplyTemplateVars renders {% %} template actions in content and then expands the
+// legacy {{TOKEN}} placeholders. Unknown tokens are left in place. Lines that hold
+// only a block action ({% if %}, {% range %}, {% end %}...) are removed entirely so
+// conditional sections don't leave blank lines behind.
+func ApplyTemplateVars(content string, vars *TemplateVars) (string, error) {
+	if strings.Contains(content, leftDelim) {
+		content = standaloneBlockPattern.ReplaceAllString(content, "$1")
+
+		funcs := templateFuncs()
+		funcs["feature"] = vars.HasFeature
+
+		tmpl, err := template.New("content").
+			Delims(leftDelim, rightDelim).
+			Option("missingkey=error").
+			Funcs(funcs).
+			Parse(content)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse template: %w", err)
+		}
+
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, vars.templateData()); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+		content = sb.String()
+	}
+
+	tokens := vars.Tokens()
+	return tokenPattern.ReplaceAllStringFunc(content, func(match string) string {
+		if value, ok := tokens[match[2:len(match)-2]]; ok {
+			return value
+		}
+		return match
+	}), nil
+}
+
+// Placeholder is a reference to a template variable found in template content
+type Placeholder struct {
+	Name string
+	Line int
+}
+
+// FindPlaceholders returns every variable referenced in content, either as a legacy
+// {{TOKEN}} placeholder or as .TOKEN inside a {% %} action, in content order
+func FindPlaceholders(content string) []Placeholder {
+	var found []Placeholder
+	lineAt := func(offset int) int {
+		return strings.Count(content[:offset], "\n") + 1
+	}
+	for _, m := range tokenPattern.FindAllStringSubmatchIndex(content, -1) {
+		found = append(found, Placeholder{content[m[2]:m[3]], lineAt(m[0])})
+	}
+	for _, action := range actionPattern.FindAllStringIndex(content, -1) {
+		for _, m := range fieldPattern.FindAllStringSubmatchIndex(content[action[0]:action[1]], -1) {
+			found = append(found, Placeholder{content[action[0]+m[2] : action[0]+m[3]], lineAt(action[0] + m[0])})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b Placeholder) int { return a.Line - b.Line })
+	return found
+}
+
+// ApplyTemplatePath applies template variables to each segment of a slash- or
+// OS-separated relative path, so templates may contain paths like pkg/{{PROJECT_NAME}}/.
+// An empty result means a segment rendered to nothing (for example a directory wrapped
+// in an unselected feature block) and the entry should be skipped.
+func ApplyTemplatePath(relPath string, vars *TemplateVars) (string, error) {
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	for i, segment := range segments {
+		rendered, err := ApplyTemplateVars(segment, vars)
+		if err != nil {
+			return "", fmt.Errorf("path %s: %w", relPath, err)
+		}
+		rendered = strings.TrimSpace(rendered)
+		if rendered == "" {
+			return "", nil
+		}
+		segments[i] = rendered
+	}
+
+	result := filepath.Clean(filepath.FromSlash(strings.Join(segments, "/")))
+	if filepath.IsAbs(result) || result == ".." || strings.HasPrefix(result, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s renders outside the project as %s", relPath, result)
+	}
+	return result, nil
+}
+
+// ReverseTemplatePath reverses template variable replacements in each segment of a
+// relative path (for create mode)
+func ReverseTemplatePath(relPath string, vars *TemplateVars) string {
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	for i, segment := range segments {
+		segments[i] = ReverseTemplateVars(segment, vars)
+	}
+	return filepath.FromSlash(strings.Join(segments, "/"))
 }
@@ -0,0 +1,429 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testVars() *TemplateVars {
+	return &TemplateVars{
+		ProjectName:    "my-app",
+		ProjectProper:  "My-app",
+		PublisherName:  "YourCompany",
+		PublisherEmail: "your_email@your_company.com",
+		Organization:   "TrueBlocks, LLC",
+		OrgName:        "TrueBlocks",
+		OrgLower:       "trueblocks",
+		Slug:           "trueblocks-my-app",
+		Github:         "github.com/TrueBlocks/my-app",
+		Domain:         "trueblocks.io",
+		Chifra:         "github.com/TrueBlocks/trueblocks-chifra/v6",
+		ProjectCases:   NewCaseVariants("my-app"),
+		OrgCases:       NewCaseVariants("TrueBlocks"),
+	}
+}
+
+func TestApplyTemplateVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "legacy tokens",
+			content: "module {{GITHUB}} by {{ORGANIZATION}}",
+			want:    "module github.com/TrueBlocks/my-app by TrueBlocks, LLC",
+		},
+		{
+			name:    "packages path",
+			content: `import "{{PACKAGES}}/types"`,
+			want:    `import "github.com/TrueBlocks/my-app/pkg/types"`,
+		},
+		{
+			name:    "unknown tokens are left alone",
+			content: "{{PROJET_NAME}} {{ flex: 1 }} {{- range .Structures }}",
+			want:    "{{PROJET_NAME}} {{ flex: 1 }} {{- range .Structures }}",
+		},
+		{
+			name:    "engine actions with functions",
+			content: "type {% pascal .PROJECT_NAME %}Service; const {% .PROJECT_NAME | screaming %}_PORT",
+			want:    "type MyAppService; const MY_APP_PORT",
+		},
+		{
+			name:    "engine and legacy tokens together",
+			content: `{% join "/" (split "-" .PROJECT_NAME) %} {{SLUG}}`,
+			want:    "my/app trueblocks-my-app",
+		},
+		{
+			name:    "default for empty value",
+			content: `{% default "none" "" %}`,
+			want:    "none",
+		},
+		{
+			name:    "unknown variable in action is an error",
+			content: "{% .PROJET_NAME %}",
+			wantErr: true,
+		},
+		{
+			name:    "malformed action is an error",
+			content: "{% if %}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTemplateVars(tt.content, testVars())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ApplyTemplateVars() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyTemplateVars() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyTemplateVars() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionalBlocks(t *testing.T) {
+	content := `import (
+	"fmt"
+	{% if feature "dalle" %}
+	"{{DALLE}}"
+	{% end %}
+)
+{% range .FEATURES %}
+// feature: {% . %}
+{% end %}
+{% if not (feature "ai") %}no ai{% end %}
+`
+
+	tests := []struct {
+		name     string
+		features []string
+		want     string
+	}{
+		{
+			name: "no features",
+			want: "import (\n\t\"fmt\"\n)\nno ai\n",
+		},
+		{
+			name:     "dalle selected",
+			features: []string{"dalle"},
+			want:     "import (\n\t\"fmt\"\n\t\"github.com/TrueBlocks/trueblocks-dalle/v2\"\n)\n// feature: dalle\nno ai\n",
+		},
+		{
+			name:     "dalle and ai selected",
+			features: []string{"dalle", "ai"},
+			want:     "import (\n\t\"fmt\"\n\t\"github.com/TrueBlocks/trueblocks-dalle/v2\"\n)\n// feature: dalle\n// feature: ai\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := testVars()
+			vars.Features = tt.features
+			got, err := ApplyTemplateVars(content, vars)
+			if err != nil {
+				t.Fatalf("ApplyTemplateVars() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyTemplateVars() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseTemplateVarsRoundTrip(t *testing.T) {
+	vars := testVars()
+	original := "name: my-app\nliquid: {% raw %}{%%}\n"
+
+	reversed := ReverseTemplateVars(original, vars)
+	if strings.Contains(reversed, "my-app") {
+		t.Errorf("ReverseTemplateVars() left project name in %q", reversed)
+	}
+
+	restored, err := ApplyTemplateVars(reversed, vars)
+	if err != nil {
+		t.Fatalf("ApplyTemplateVars() unexpected error: %v", err)
+	}
+	if restored != original {
+		t.Errorf("round trip = %q, want %q", restored, original)
+	}
+}
+
+func TestCaseVariantTokens(t *testing.T) {
+	vars := testVars()
+
+	original := "var MyApp = myApp + my_app // MY_APP by True Blocks (TRUE_BLOCKS, trueBlocks)\nname: my-app, My-app"
+	want := "var {{PROJECT_PASCAL}} = {{PROJECT_CAMEL}} + {{PROJECT_SNAKE}} // {{PROJECT_SCREAMING}} by {{ORG_TITLE}} ({{ORG_SCREAMING}}, {{ORG_CAMEL}})\nname: {{PROJECT_NAME}}, {{PROJECT_PROPER}}"
+	if got := ReverseTemplateVars(original, vars); got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+	if got, _ := ApplyTemplateVars(want, vars); got != original {
+		t.Errorf("ApplyTemplateVars() = %q, want %q", got, original)
+	}
+}
+
+func TestReversePublisher(t *testing.T) {
+	vars := testVars()
+	vars.PublisherName = "Jane Doe"
+	vars.PublisherEmail = "jane@trueblocks.io"
+
+	content := "Copyright Jane Doe <jane@trueblocks.io>, see https://trueblocks.io"
+	want := "Copyright {{PUBLISHER_NAME}} <{{PUBLISHER_EMAIL}}>, see https://{{DOMAIN}}"
+	if got := ReverseTemplateVars(content, vars); got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	tests := []struct {
+		github string
+		want   string
+	}{
+		{"github.com/acme", "github.com/acme/acme-widget"},
+		{"github.com/acme/widget", "github.com/acme/widget"},
+		{"https://github.com/acme/widget.git", "github.com/acme/widget"},
+		{"git@gitlab.com:acme/widget.git", "gitlab.com/acme/widget"},
+		{"gitlab.com/acme/group/widget", "gitlab.com/acme/group/widget"},
+		{"acme", "github.com/acme/acme-widget"},
+		{"acme/widget", "github.com/acme/widget"},
+		{"gitlab.com", "gitlab.com/Acme/acme-widget"},
+		{"", "github.com/Acme/acme-widget"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.github, func(t *testing.T) {
+			if got := ModulePath(tt.github, "Acme", "acme-widget"); got != tt.want {
+				t.Errorf("ModulePath(%q) = %q, want %q", tt.github, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleTokens(t *testing.T) {
+	vars := testVars()
+	vars.Organization = "Acme, Inc"
+	vars.OrgName = "Acme"
+	vars.OrgLower = "acme"
+	vars.Slug = "acme-my-app"
+	vars.Github = "github.com/acme-corp"
+
+	original := "module github.com/acme-corp/acme-my-app\nimport \"github.com/acme-corp/acme-my-app/pkg/types\"\nimport \"github.com/acme-corp/acme-my-app/app\"\n"
+	want := "module {{MODULE}}\nimport \"{{PACKAGES}}/types\"\nimport \"{{APP}}\"\n"
+	if got := ReverseTemplateVars(original, vars); got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+	if got, _ := ApplyTemplateVars(want, vars); got != original {
+		t.Errorf("ApplyTemplateVars() = %q, want %q", got, original)
+	}
+
+	// A template that declares MODULE moves the derived paths with it
+	vars.Extra = map[string]string{"MODULE": "example.com/tools/my-app"}
+	if got, _ := ApplyTemplateVars(`"{{PACKAGES}}/types" "{{APP}}"`, vars); got != `"example.com/tools/my-app/pkg/types" "example.com/tools/my-app/app"` {
+		t.Errorf("ApplyTemplateVars() with MODULE = %q", got)
+	}
+}
+
+func TestReverseTemplateVarsBoundaries(t *testing.T) {
+	vars := testVars()
+	vars.ProjectName = "app"
+	vars.ProjectProper = "App"
+	vars.Slug = "trueblocks-app"
+
+	content := "name: app\nconst mapping = appName;\nimport App from './App';\n"
+
+	hits := FindAmbiguous(content, vars)
+	if len(hits) != 2 {
+		t.Fatalf("FindAmbiguous() found %d hits, want 2: %+v", len(hits), hits)
+	}
+	if hits[0].Line != 2 || hits[0].Value != "app" || hits[0].Token != "{{PROJECT_NAME}}" {
+		t.Errorf("FindAmbiguous() first hit = %+v", hits[0])
+	}
+	if hits[0].Context != "const mapping = appName;" {
+		t.Errorf("FindAmbiguous() context = %q", hits[0].Context)
+	}
+
+	want := "name: {{PROJECT_NAME}}\nconst mapping = appName;\nimport {{PROJECT_PROPER}} from './{{PROJECT_PROPER}}';\n"
+	if got := ReverseTemplateVars(content, vars); got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+
+	// Accept only the hit inside appName
+	got := ReverseTemplateVarsFunc(content, vars, func(hit Hit) bool {
+		return hit.Offset == hits[1].Offset
+	})
+	want = "name: {{PROJECT_NAME}}\nconst mapping = {{PROJECT_NAME}}Name;\nimport {{PROJECT_PROPER}} from './{{PROJECT_PROPER}}';\n"
+	if got != want {
+		t.Errorf("ReverseTemplateVarsFunc() = %q, want %q", got, want)
+	}
+}
+
+func TestReverseTemplateVarsPreserves(t *testing.T) {
+	vars := testVars()
+	vars.ProjectName = "dalledress"
+	vars.ProjectProper = "Dalledress"
+
+	content := "dalledressStore := getDalledressFields(dalledress)"
+	want := "dalledressStore := getDalledressFields({{PROJECT_NAME}})"
+	if got := ReverseTemplateVars(content, vars); got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadPreserves(t *testing.T) {
+	projectDir := t.TempDir()
+	templateDir := t.TempDir()
+	missingDir := filepath.Join(t.TempDir(), "missing")
+
+	if err := os.WriteFile(filepath.Join(projectDir, PreserveFile), []byte("# our names\nmy-app-legacy\n\n  Explorers  \n"), 0o644); err != nil {
+		t.Fatalf("Failed to write preserve file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, PreserveFile), []byte("my-app-legacy\nMyAppWidget\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write preserve file: %v", err)
+	}
+
+	phrases, err := LoadPreserves(projectDir, templateDir, missingDir)
+	if err != nil {
+		t.Fatalf("LoadPreserves() unexpected error: %v", err)
+	}
+	if want := len(DefaultPreserves) + 2; len(phrases) != want {
+		t.Errorf("LoadPreserves() returned %d phrases, want %d", len(phrases), want)
+	}
+
+	vars := testVars()
+	vars.Preserves = phrases
+	content := "my-app and my-app-legacy"
+	if got, want := ReverseTemplateVars(content, vars), "{{PROJECT_NAME}} and my-app-legacy"; got != want {
+		t.Errorf("ReverseTemplateVars() = %q, want %q", got, want)
+	}
+}
+
+func TestTemplatePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"plain path", "pkg/types/types.go", "pkg/types/types.go", false},
+		{"token directory", "pkg/{{PROJECT_NAME}}/main.go", "pkg/my-app/main.go", false},
+		{"token file name", "cmd/{{SLUG}}.go", "cmd/trueblocks-my-app.go", false},
+		{"engine action", "{% snake .PROJECT_NAME %}_test.go", "my_app_test.go", false},
+		{"unselected feature", `{% if feature "dalle" %}dalle{% end %}/dalle.go`, "", false},
+		{"escapes project", "{% \"..\" %}/x.go", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTemplatePath(filepath.FromSlash(tt.template), testVars())
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ApplyTemplatePath() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyTemplatePath() unexpected error: %v", err)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("ApplyTemplatePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	reversed := ReverseTemplatePath(filepath.FromSlash("pkg/my-app/trueblocks-my-app.go"), testVars())
+	if want := filepath.FromSlash("pkg/{{PROJECT_NAME}}/{{SLUG}}.go"); reversed != want {
+		t.Errorf("ReverseTemplatePath() = %q, want %q", reversed, want)
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		data []byte
+		want bool
+	}{
+		{"go source", "main.go", []byte("package main\n"), false},
+		{"utf8 markdown", "README.md", []byte("# Café ✅\n"), false},
+		{"empty file", ".gitkeep", []byte{}, false},
+		{"png by extension", "build/appicon.png", []byte("looks like text"), true},
+		{"font by extension", "fonts/Inter.WOFF2", []byte("abc"), true},
+		{"nul byte", "data.bin", []byte("abc\x00def"), true},
+		{"invalid utf8", "blob", []byte{0xff, 0xfe, 0x41}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.path, tt.data); got != tt.want {
+				t.Errorf("IsBinary(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaseFunctions(t *testing.T) {
+	tests := []struct {
+		in                                            string
+		kebab, snake, screaming, camel, pascal, title string
+	}{
+		{"my-app", "my-app", "my_app", "MY_APP", "myApp", "MyApp", "My App"},
+		{"MyAPIServer", "my-api-server", "my_api_server", "MY_API_SERVER", "myApiServer", "MyApiServer", "My Api Server"},
+		{"dalledress", "dalledress", "dalledress", "DALLEDRESS", "dalledress", "Dalledress", "Dalledress"},
+		{"open approvals 2", "open-approvals-2", "open_approvals_2", "OPEN_APPROVALS_2", "openApprovals2", "OpenApprovals2", "Open Approvals 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := toKebab(tt.in); got != tt.kebab {
+				t.Errorf("toKebab(%q) = %q, want %q", tt.in, got, tt.kebab)
+			}
+			if got := toSnake(tt.in); got != tt.snake {
+				t.Errorf("toSnake(%q) = %q, want %q", tt.in, got, tt.snake)
+			}
+			if got := toScreamingSnake(tt.in); got != tt.screaming {
+				t.Errorf("toScreamingSnake(%q) = %q, want %q", tt.in, got, tt.screaming)
+			}
+			if got := toCamel(tt.in); got != tt.camel {
+				t.Errorf("toCamel(%q) = %q, want %q", tt.in, got, tt.camel)
+			}
+			if got := toPascal(tt.in); got != tt.pascal {
+				t.Errorf("toPascal(%q) = %q, want %q", tt.in, got, tt.pascal)
+			}
+			if got := toTitle(tt.in); got != tt.title {
+				t.Errorf("toTitle(%q) = %q, want %q", tt.in, got, tt.title)
+			}
+		})
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	tests := []struct{ singular, plural string }{
+		{"app", "apps"},
+		{"entry", "entries"},
+		{"key", "keys"},
+		{"box", "boxes"},
+		{"branch", "branches"},
+		{"class", "classes"},
+		{"NAME", "NAMES"},
+	}
+
+	for _, tt := range tests {
+		if got := toPlural(tt.singular); got != tt.plural {
+			t.Errorf("toPlural(%q) = %q, want %q", tt.singular, got, tt.plural)
+		}
+		if got := toSingular(tt.plural); got != tt.singular {
+			t.Errorf("toSingular(%q) = %q, want %q", tt.plural, got, tt.singular)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package processor
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PreserveFile lists phrases, one per line, that --create must never templatize. It is
+// read from both the source project and the template being created.
+const PreserveFile = ".create-local-app-preserve"
+
+// DefaultPreserves are phrases that contain a project's values but must never be
+// templatized. Projects extend this list with a PreserveFile.
+var DefaultPreserves = []string{
+	"Block explorer",
+	"Block Explorer",
+	"class Explorer",
+	"dalleDressStore",
+	"Explorers",
+	"explorers: Explorer[];",
+	"ExportApprovals",
+	"ExportsOpenApprovals",
+	"getOpenapprovalsFields",
+	"getOpenApprovalsStore",
+	"json:\"dalledress\"",
+	"local block explorer",
+	"Local Explorer",
+	"localExplorer",
+	"LocalExplorer",
+	"new Explorer",
+	"Open Approvals",
+	"openapprovals",
+	"OpenApprovals",
+	"OPENAPPROVALS",
+	"openapprovalsFacet",
+	"openapprovalsStore",
+	"openapprovalsStoreMu",
+	"pageData?.dalledress",
+	"pageData.dalledress",
+	"remote block explorer",
+	"Remote Explorer",
+	"remoteExplorer",
+	"RemoteExplorer",
+	"SortOpenApprovals",
+	"this.explorers = this.convertValues(source[\"explorers\"], Explorer",
+	"TokensApprovals",
+	"dalledressStore",
+	"dalledressStoreMu",
+	"dresses-dalledress",
+	"\"dalledress\":",
+	"getDalledressFields",
+	"Store:         \"dalledress\"",
+	"this.dalledress = this.convertValues(source[\"dalledress\"], model.DalleDress);",
+	"dalledress: model.DalleDress[];",
+	"dalledress:",
+}
+
+// LoadPreserves returns DefaultPreserves merged with the phrases listed in the
+// PreserveFile of each directory. Blank lines and lines starting with # are ignored.
+// Missing files are not an error.
+func LoadPreserves(dirs ...string) ([]string, error) {
+	phrases := slices.Clone(DefaultPreserves)
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, PreserveFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, PreserveFile), err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") || slices.Contains(phrases, line) {
+				continue
+			}
+			phrases = append(phrases, line)
+		}
+	}
+	return phrases, nil
+}
+
+// Hit is an occurrence of a project value that sits inside a larger word, such as a
+// project named "app" inside "mapping". Hits are only templatized when accepted.
+type Hit struct {
+	Offset  int    // byte offset of the match in the original content
+	Line    int    // 1-based line number of the match
+	Value   string // the matched project value
+	Token   string // the placeholder that would replace it, e.g. {{PROJECT_NAME}}
+	Context string // the text surrounding the match
+}
+
+// replacement pairs a project value with the placeholder it reverses to
+type replacement struct {
+	value string
+	token string
+}
+
+// reverseReplacements returns the values to templatize in priority order. Earlier
+// entries claim their text first, so later, shorter values never match inside them.
+func (vars *TemplateVars) reverseReplacements() []replacement {
+	var result []replacement
+
+	// Template-specific values first, longest first, since they often contain the built-in ones
+	extras := slices.Collect(maps.Keys(vars.Extra))
+	slices.SortFunc(extras, func(a, b string) int {
+		if d := len(vars.Extra[b]) - len(vars.Extra[a]); d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+	for _, name := range extras {
+		result = append(result, replacement{vars.Extra[name], "{{" + name + "}}"})
+	}
+
+	// Module paths come from the same derived tokens used when generating
+	tokens := vars.Tokens()
+	result = append(result,
+		replacement{tokens["SDK"], "{{SDK}}"},
+		replacement{tokens["DALLE"], "{{DALLE}}"},
+		replacement{tokens["PACKAGES"], "{{PACKAGES}}"},
+		replacement{tokens["APP"], "{{APP}}"},
+		replacement{tokens["MODULE"], "{{MODULE}}"},
+		replacement{vars.Chifra, "{{CHIFRA}}"},
+		replacement{vars.PublisherEmail, "{{PUBLISHER_EMAIL}}"},
+		replacement{vars.Domain, "{{DOMAIN}}"},
+		replacement{vars.Github, "{{GITHUB}}"},
+		replacement{vars.PublisherName, "{{PUBLISHER_NAME}}"},
+		replacement{vars.Slug, "{{SLUG}}"},
+	)
+
+	names := []replacement{
+		{vars.OrgName, "{{ORG_NAME}}"},
+		{vars.OrgLower, "{{ORG_LOWER}}"},
+		{vars.Organization, "{{ORGANIZATION}}"},
+		{vars.ProjectName, "{{PROJECT_NAME}}"},
+		{vars.ProjectProper, "{{PROJECT_PROPER}}"},
+	}
+	result = append(result, vars.caseReplacements(names)...)
+	result = append(result, names...)
+
+	return slices.DeleteFunc(result, func(r replacement) bool { return r.value == "" })
+}
+
+// caseReplacements returns the case variants of the project and organization names,
+// longest first, leaving out any value already covered by one of names
+func (vars *TemplateVars) caseReplacements(names []replacement) []replacement {
+	tokens := vars.ProjectCases.tokens("PROJECT_")
+	maps.Copy(tokens, vars.OrgCases.tokens("ORG_"))
+
+	var result []replacement
+	for _, name := range slices.Sorted(maps.Keys(tokens)) {
+		value := tokens[name]
+		covered := func(r replacement) bool { return r.value == value }
+		if slices.ContainsFunc(names, covered) || slices.ContainsFunc(result, covered) {
+			continue
+		}
+		result = append(result, replacement{value, "{{" + name + "}}"})
+	}
+	slices.SortStableFunc(result, func(a, b replacement) int { return len(b.value) - len(a.value) })
+	return result
+}
+
+// ReverseTemplateVars reverses template variable replacements (for create mode). Values
+// are only replaced where they stand alone as a word; occurrences inside a larger word
+// are left as they are (see ReverseTemplateVarsFunc and FindAmbiguous).
+func ReverseTemplateVars(content string, vars *TemplateVars) string {
+	return ReverseTemplateVarsFunc(content, vars, nil)
+}
+
+// ReverseTemplateVarsFunc reverses template variable replacements, calling accept for
+// every ambiguous occurrence to decide whether it is templatized as well. A nil accept
+// rejects every ambiguous occurrence.
+func ReverseTemplateVarsFunc(content string, vars *TemplateVars, accept func(Hit) bool) string {
+	output, _ := reverse(content, vars, accept)
+	return output
+}
+
+// FindAmbiguous returns every occurrence of a project value in content that sits inside
+// a larger word and would therefore not be templatized without confirmation
+func FindAmbiguous(content string, vars *TemplateVars) []Hit {
+	_, hits := reverse(content, vars, nil)
+	return hits
+}
+
+// span is a claimed region of the original content. An empty token marks a preserved
+// phrase that is copied as-is.
+type span struct {
+	start, end int
+	token      string
+}
+
+// FindLiterals returns every occurrence of a project value in content that stands alone
+// as a word and would be templatized by ReverseTemplateVars. In a template these are
+// values that --create failed to replace.
+func FindLiterals(content string, vars *TemplateVars) []Hit {
+	spans, _ := claimSpans(content, vars, nil)
+
+	var literals []Hit
+	for _, sp := range spans {
+		if sp.token != "" {
+			literals = append(literals, newHit(content, sp.start, replacement{content[sp.start:sp.end], sp.token}))
+		}
+	}
+	return literals
+}
+
+// reverse replaces the spans claimed in content with their placeholders, escaping
+// everything else
+func reverse(content string, vars *TemplateVars, accept func(Hit) bool) (string, []Hit) {
+	spans, hits := claimSpans(content, vars, accept)
+
+	var sb strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		sb.WriteString(escapeDelims(content[pos:sp.start]))
+		if sp.token == "" {
+			sb.WriteString(escapeDelims(content[sp.start:sp.end]))
+		} else {
+			sb.WriteString(sp.token)
+		}
+		pos = sp.end
+	}
+	sb.WriteString(escapeDelims(content[pos:]))
+
+	return sb.String(), hits
+}
+
+// claimSpans finds every occurrence of each replacement value in the original content,
+// letting earlier replacements and preserved phrases claim their text first. Working on
+// offsets into the original content keeps hits stable no matter which are accepted.
+// The spans are returned in content order.
+func claimSpans(content string, vars *TemplateVars, accept func(Hit) bool) ([]span, []Hit) {
+	taken := make([]bool, len(content))
+	isFree := func(start, end int) bool {
+		return !slices.Contains(taken[start:end], true)
+	}
+	claim := func(start, end int) {
+		for i := start; i < end; i++ {
+			taken[i] = true
+		}
+	}
+
+	phrases := vars.Preserves
+	if phrases == nil {
+		phrases = DefaultPreserves
+	}
+	// Longer phrases claim their text first so a shorter phrase can't split them
+	phrases = slices.Clone(phrases)
+	slices.SortStableFunc(phrases, func(a, b string) int { return len(b) - len(a) })
+
+	var spans []span
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		for _, start := range indexAll(content, phrase) {
+			if isFree(start, start+len(phrase)) {
+				claim(start, start+len(phrase))
+				spans = append(spans, span{start, start + len(phrase), ""})
+			}
+		}
+	}
+
+	var hits []Hit
+	for _, r := range vars.reverseReplacements() {
+		for _, start := range indexAll(content, r.value) {
+			end := start + len(r.value)
+			if !isFree(start, end) {
+				continue
+			}
+			if !atWordBoundary(content, start, end) {
+				hit := newHit(content, start, r)
+				hits = append(hits, hit)
+				if accept == nil || !accept(hit) {
+					continue
+				}
+			}
+			claim(start, end)
+			spans = append(spans, span{start, end, r.token})
+		}
+	}
+
+	slices.SortFunc(spans, func(a, b span) int { return a.start - b.start })
+
+	return spans, hits
+}
+
+// escapeDelims escapes literal template delimiters so the project's own text survives rendering
+func escapeDelims(text string) string {
+	return strings.ReplaceAll(text, leftDelim, leftDelim+`"`+leftDelim+`"`+rightDelim)
+}
+
+// indexAll returns the offset of every occurrence of value in content, including overlapping ones
+func indexAll(content, value string) []int {
+	var offsets []int
+	for pos := 0; ; {
+		i := strings.Index(content[pos:], value)
+		if i < 0 {
+			return offsets
+		}
+		offsets = append(offsets, pos+i)
+		pos += i + 1
+	}
+}
+
+// isWordRune reports whether r can be part of an identifier
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// atWordBoundary reports whether the match content[start:end] stands alone, that is, it
+// does not continue a word on either side. A value that itself starts or ends with
+// punctuation (such as a module path) only needs a boundary on its word-like ends.
+func atWordBoundary(content string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(content[start:end])
+	last, _ := utf8.DecodeLastRuneInString(content[start:end])
+
+	if start > 0 && isWordRune(first) {
+		if prev, _ := utf8.DecodeLastRuneInString(content[:start]); isWordRune(prev) {
+			return false
+		}
+	}
+	if end < len(content) && isWordRune(last) {
+		if next, _ := utf8.DecodeRuneInString(content[end:]); isWordRune(next) {
+			return false
+		}
+	}
+	return true
+}
+
+// contextWidth is how much text on either side of a hit is shown as context
+const contextWidth = 40
+
+// newHit describes an ambiguous match with its line number and surrounding text
+func newHit(content string, start int, r replacement) Hit {
+	end := start + len(r.value)
+
+	lineStart := strings.LastIndexByte(content[:start], '\n') + 1
+	lineEnd := len(content)
+	if i := strings.IndexByte(content[end:], '\n'); i >= 0 {
+		lineEnd = end + i
+	}
+
+	from := max(lineStart, start-contextWidth)
+	to := min(lineEnd, end+contextWidth)
+	context := content[from:to]
+	if !utf8.ValidString(context) {
+		context = strings.ToValidUTF8(context, "")
+	}
+
+	return Hit{
+		Offset:  start,
+		Line:    strings.Count(content[:start], "\n") + 1,
+		Value:   r.value,
+		Token:   r.token,
+		Context: strings.TrimSpace(context),
+	}
+}
@@ -0,0 +1,360 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxLinkHops bounds how many symbolic links are followed when resolving a path
+const maxLinkHops = 255
+
+// extractTarGz extracts a gzipped tar archive into destDir. An entry whose name is
+// absolute, climbs out of destDir or passes through a symbolic link is rejected with an
+// error. Symbolic links are recreated only if they resolve inside destDir and hard links
+// only if they name a file already extracted; these and any other special entries that
+// cannot be extracted are skipped with a warning. The metadata of an exported archive
+// (see Export) is returned rather than extracted; other archives return nil.
+func extractTarGz(r io.Reader, destDir string) (*ArchiveInfo, error) {
+	// Create gzip reader
+	gzReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gzReader.Close()
+
+	// Create tar reader
+	tarReader := tar.NewReader(gzReader)
+	x := &extractor{destDir: destDir}
+	var info *ArchiveInfo
+
+	// Extract files
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if isResourceFork(header.Name) || header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if path.Clean(header.Name) == ArchiveInfoFile && header.Typeflag == tar.TypeReg {
+			if info, err = readArchiveInfo(tarReader); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Handle different file types
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(header.Name, mode)
+		case tar.TypeReg:
+			err = x.file(header.Name, tarReader, mode)
+		case tar.TypeSymlink:
+			err = x.symlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(header.Name, header.Linkname)
+		default:
+			err = x.skip(header.Name, fmt.Sprintf("unsupported entry type %q", header.Typeflag))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	x.finish()
+	return info, nil
+}
+
+// extractZip extracts the zip archive at zipPath into destDir with the same checks as
+// extractTarGz
+func extractZip(zipPath, destDir string) error {
+	zipReader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip file %s: %w", zipPath, err)
+	}
+	defer zipReader.Close()
+
+	x := &extractor{destDir: destDir}
+	for _, f := range zipReader.File {
+		if isResourceFork(f.Name) {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.dir(f.Name, mode)
+		case mode.IsRegular(), mode&fs.ModeSymlink != 0:
+			err = extractZipFile(x, f)
+		default:
+			err = x.skip(f.Name, "unsupported entry type "+mode.Type().String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	x.finish()
+	return nil
+}
+
+// extractZipFile extracts one file or symbolic link from a zip archive
+func extractZipFile(x *extractor, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if f.Mode()&fs.ModeSymlink == 0 {
+		return x.file(f.Name, rc, f.Mode())
+	}
+	// A zipped symbolic link holds its target as its content
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return x.symlink(f.Name, string(target))
+}
+
+// copyDir copies the directory at srcDir into destDir with the same checks as
+// extractTarGz, leaving out any .git directory
+func copyDir(srcDir, destDir string) error {
+	x := &extractor{destDir: destDir}
+	err := filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(srcDir, path)
+		name := filepath.ToSlash(relPath)
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return x.dir(name, info.Mode())
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return x.symlink(name, target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return x.file(name, f, info.Mode())
+		default:
+			return x.skip(name, "unsupported file type "+info.Mode().Type().String())
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", srcDir, err)
+	}
+
+	x.finish()
+	return nil
+}
+
+// isResourceFork reports whether an archive entry is macOS metadata (._filename or a
+// __MACOSX folder) rather than part of the template
+func isResourceFork(name string) bool {
+	return strings.Contains(name, "/._") || strings.HasPrefix(filepath.Base(name), "._") ||
+		name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/")
+}
+
+// extractor writes the entries of an archive or directory into destDir, refusing any
+// that would end up outside it
+type extractor struct {
+	destDir  string
+	symlinks []string // names of the symbolic links created, checked again by finish
+}
+
+// skip reports an entry that is not extracted
+func (x *extractor) skip(name, reason string) error {
+	fmt.Printf("Warning: skipped archive entry %s: %s\n", name, reason)
+	return nil
+}
+
+// dir creates a directory entry
+func (x *extractor) dir(name string, mode fs.FileMode) error {
+	destPath, err := entryPath(x.destDir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destPath, mode.Perm()); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", destPath, err)
+	}
+	return nil
+}
+
+// file writes a regular file entry with the content read from r
+func (x *extractor) file(name string, r io.Reader, mode fs.FileMode) error {
+	destPath, err := entryPath(x.destDir, name)
+	if err != nil {
+		return err
+	}
+	if err := extractRegularFile(r, destPath, mode); err != nil {
+		return fmt.Errorf("failed to extract file %s: %w", destPath, err)
+	}
+	return nil
+}
+
+// symlink recreates a symbolic link entry, unless it points outside destDir
+func (x *extractor) symlink(name, target string) error {
+	destPath, err := entryPath(x.destDir, name)
+	if err != nil {
+		return err
+	}
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return x.skip(name, "symbolic link to absolute path "+target)
+	}
+	if err := replaceWith(destPath, func() error { return os.Symlink(target, destPath) }); err != nil {
+		return fmt.Errorf("failed to create symbolic link %s: %w", destPath, err)
+	}
+	if !insideRoot(x.destDir, name) {
+		os.Remove(destPath)
+		return x.skip(name, "symbolic link to "+target+" points outside the template")
+	}
+	x.symlinks = append(x.symlinks, name)
+	return nil
+}
+
+// hardlink recreates a hard link entry, unless its target is not a file already extracted
+func (x *extractor) hardlink(name, target string) error {
+	destPath, err := entryPath(x.destDir, name)
+	if err != nil {
+		return err
+	}
+	source, err := entryPath(x.destDir, target)
+	if info, statErr := os.Lstat(source); err != nil || statErr != nil || !info.Mode().IsRegular() {
+		return x.skip(name, "hard link to "+target+" which is not a file in the template")
+	}
+	if err := replaceWith(destPath, func() error { return os.Link(source, destPath) }); err != nil {
+		return fmt.Errorf("failed to create hard link %s: %w", destPath, err)
+	}
+	return nil
+}
+
+// finish checks every symbolic link again against the finished tree. A link that
+// pointed inside when it was made can point outside once the links it goes through exist.
+func (x *extractor) finish() {
+	for _, name := range x.symlinks {
+		if !insideRoot(x.destDir, name) {
+			os.Remove(filepath.Join(x.destDir, filepath.FromSlash(name)))
+			x.skip(name, "symbolic link points outside the template")
+		}
+	}
+}
+
+// entryPath returns where the archive entry name is extracted to, or an error if the
+// name is absolute, climbs out of destDir, or passes through a symbolic link
+func entryPath(destDir, name string) (string, error) {
+	rel := filepath.FromSlash(name)
+	if path.IsAbs(name) || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("archive entry %s would be written outside %s", name, destDir)
+	}
+	rel = filepath.Clean(rel)
+
+	dir := destDir
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("archive entry %s would be written through the symbolic link %s", name, dir)
+		}
+	}
+	return filepath.Join(destDir, rel), nil
+}
+
+// replaceWith removes anything but a directory at path, then calls create
+func replaceWith(path string, create func() error) error {
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return create()
+}
+
+// insideRoot reports whether the slash-separated path rel, resolved against root and
+// following any symbolic links below root, stays inside root. Missing entries are
+// taken as they are.
+func insideRoot(root, rel string) bool {
+	var resolved []string
+	pending := strings.Split(rel, "/")
+	for hops := 0; len(pending) > 0; {
+		part := pending[0]
+		pending = pending[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return false
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, part)
+		target, err := os.Readlink(filepath.Join(root, filepath.Join(resolved...)))
+		if err != nil {
+			// Not a symbolic link
+			continue
+		}
+		if hops++; hops > maxLinkHops || path.IsAbs(target) || filepath.IsAbs(target) {
+			return false
+		}
+		resolved = resolved[:len(resolved)-1]
+		pending = append(strings.Split(filepath.ToSlash(target), "/"), pending...)
+	}
+	return true
+}
+
+// extractRegularFile writes the content read from r to destPath
+func extractRegularFile(r io.Reader, destPath string, mode fs.FileMode) error {
+	// Create destination directory if it doesn't exist
+	destDir := filepath.Dir(destPath)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", destDir, err)
+	}
+
+	// Never write through a symbolic link left by an earlier entry
+	if err := replaceWith(destPath, func() error { return nil }); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", destPath, err)
+	}
+
+	// Create the file
+	outFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", destPath, err)
+	}
+	defer outFile.Close()
+
+	// Copy file contents
+	if _, err := io.Copy(outFile, r); err != nil {
+		return fmt.Errorf("failed to write file contents: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package templates
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is one member of a test archive
+type entry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+// makeTarGz builds a gzipped tar archive holding entries in order
+func makeTarGz(t *testing.T, entries []entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0o755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("WriteHeader(%s) unexpected error: %v", e.name, err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Write(%s) unexpected error: %v", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	return &buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr string
+		present []string // paths that must exist after extraction
+		absent  []string // paths that must not
+	}{
+		{
+			name: "regular template",
+			entries: []entry{
+				{name: "tpl/", typeflag: tar.TypeDir},
+				{name: "tpl/README.md", typeflag: tar.TypeReg, body: "# readme\n"},
+				{name: "tpl/._README.md", typeflag: tar.TypeReg, body: "resource fork"},
+				{name: "./tpl/src/main.go", typeflag: tar.TypeReg, body: "package main\n"},
+			},
+			present: []string{"tpl/README.md", "tpl/src/main.go"},
+			absent:  []string{"tpl/._README.md"},
+		},
+		{
+			name:    "parent directory",
+			entries: []entry{{name: "tpl/../../evil.txt", typeflag: tar.TypeReg, body: "x"}},
+			wantErr: "would be written outside",
+		},
+		{
+			name:    "absolute path",
+			entries: []entry{{name: "/tmp/evil.txt", typeflag: tar.TypeReg, body: "x"}},
+			wantErr: "would be written outside",
+		},
+		{
+			name: "write through symlink",
+			entries: []entry{
+				{name: "tpl/sub/keep.txt", typeflag: tar.TypeReg, body: "x"},
+				{name: "tpl/link", typeflag: tar.TypeSymlink, linkname: "sub"},
+				{name: "tpl/link/evil.txt", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "through the symbolic link",
+		},
+		{
+			name: "links",
+			entries: []entry{
+				{name: "tpl/sub/file.txt", typeflag: tar.TypeReg, body: "content"},
+				{name: "tpl/inside", typeflag: tar.TypeSymlink, linkname: "sub/file.txt"},
+				{name: "tpl/hard", typeflag: tar.TypeLink, linkname: "tpl/sub/file.txt"},
+				{name: "tpl/up", typeflag: tar.TypeSymlink, linkname: "../../outside"},
+				{name: "tpl/abs", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+				{name: "tpl/hardout", typeflag: tar.TypeLink, linkname: "../outside"},
+				{name: "tpl/fifo", typeflag: tar.TypeFifo},
+				// Harmless on its own, but escapes once loop points at the template root
+				{name: "tpl/later", typeflag: tar.TypeSymlink, linkname: "loop/../.."},
+				{name: "tpl/loop", typeflag: tar.TypeSymlink, linkname: "."},
+			},
+			present: []string{"tpl/inside", "tpl/hard", "tpl/loop"},
+			absent:  []string{"tpl/up", "tpl/abs", "tpl/hardout", "tpl/fifo", "tpl/later"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destDir := filepath.Join(t.TempDir(), "system")
+			if err := os.MkdirAll(destDir, 0o755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+
+			_, err := extractTarGz(makeTarGz(t, tt.entries), destDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractTarGz() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Lstat(filepath.Join(filepath.Dir(destDir), "evil.txt")); err == nil {
+					t.Errorf("extractTarGz() wrote outside the destination")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractTarGz() unexpected error: %v", err)
+			}
+			for _, rel := range tt.present {
+				if _, err := os.Lstat(filepath.Join(destDir, rel)); err != nil {
+					t.Errorf("extractTarGz() did not create %s: %v", rel, err)
+				}
+			}
+			for _, rel := range tt.absent {
+				if _, err := os.Lstat(filepath.Join(destDir, rel)); err == nil {
+					t.Errorf("extractTarGz() created %s, want it skipped", rel)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package templates
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+)
+
+// ArchiveInfoFile is the metadata entry at the root of an exported template archive. It
+// is read when the archive is extracted and never written out.
+const ArchiveInfoFile = "template-info.json"
+
+// maxArchiveInfoSize bounds the metadata entry read from an archive
+const maxArchiveInfoSize = 1 << 20
+
+// ArchiveInfo describes the template held in an exported archive
+type ArchiveInfo struct {
+	Name            string `json:"name"`
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	ToolVersion     string `json:"toolVersion"`
+	Files           int    `json:"files"`
+	Digest          string `json:"digest"` // of the template's files, checked when the archive is installed
+}
+
+// Export writes the template templateName, which may pin a version, to a .tar.gz at
+// outPath: the ArchiveInfoFile entry followed by the template's files inside a folder
+// named after it. Entries are in lexical order and carry fixed times, owners and
+// permissions, and macOS resource forks are left out, so exporting the same template
+// twice gives identical archives.
+func Export(templateName, outPath, toolVersion string) (*ArchiveInfo, error) {
+	templateDir, err := GetTemplateDir(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []treeEntry
+	err = filepath.WalkDir(templateDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(templateDir, filePath)
+		rel := filepath.ToSlash(relPath)
+		if isResourceFork(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch mode := d.Type(); {
+		case mode&fs.ModeSymlink != 0:
+			// Installing refuses links that leave the template, so don't export them
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+			if path.IsAbs(target) || filepath.IsAbs(target) || !insideRoot(templateDir, rel) {
+				fmt.Printf("Warning: skipped %s: symbolic link to %s points outside the template\n", rel, target)
+				return nil
+			}
+		case !mode.IsDir() && !mode.IsRegular():
+			fmt.Printf("Warning: skipped %s: unsupported file type %s\n", rel, mode)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, treeEntry{rel, filePath, info.Mode()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", templateDir, err)
+	}
+
+	name, _ := config.SplitTemplateRef(templateName)
+	info := &ArchiveInfo{Name: name, TemplateVersion: Version(templateDir), ToolVersion: toolVersion}
+	if info.Digest, err = digestEntries(entries); err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", templateDir, err)
+	}
+	for _, e := range entries {
+		if e.mode.IsRegular() {
+			info.Files++
+		}
+	}
+
+	// Write to a temporary file beside the output so a failed export leaves nothing behind
+	out, err := os.CreateTemp(filepath.Dir(outPath), ".export-*.tar.gz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", outPath, err)
+	}
+	defer os.Remove(out.Name())
+	if err := writeExport(out, info, entries); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+	if err := os.Chmod(out.Name(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+	if err := os.Rename(out.Name(), outPath); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+	return info, nil
+}
+
+// writeExport writes the archive described by info and entries to w
+func writeExport(w io.Writer, info *ArchiveInfo, entries []treeEntry) error {
+	gzWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := tarWriter.WriteHeader(exportHeader(ArchiveInfoFile, tar.TypeReg, 0644, int64(len(data)))); err != nil {
+		return err
+	}
+	if _, err := tarWriter.Write(data); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		name := path.Join(info.Name, e.rel)
+		switch {
+		case e.mode.IsDir():
+			err = tarWriter.WriteHeader(exportHeader(name+"/", tar.TypeDir, 0755, 0))
+		case e.mode&fs.ModeSymlink != 0:
+			var target string
+			if target, err = os.Readlink(e.path); err == nil {
+				header := exportHeader(name, tar.TypeSymlink, 0777, 0)
+				header.Linkname = filepath.ToSlash(target)
+				err = tarWriter.WriteHeader(header)
+			}
+		default:
+			err = writeExportFile(tarWriter, name, e)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzWriter.Close()
+}
+
+// writeExportFile adds a regular file to the archive as name. Only the executable bit of
+// its permissions is kept.
+func writeExportFile(tarWriter *tar.Writer, name string, e treeEntry) error {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var mode int64 = 0644
+	if e.mode&0111 != 0 {
+		mode = 0755
+	}
+	if err := tarWriter.WriteHeader(exportHeader(name, tar.TypeReg, mode, stat.Size())); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, f)
+	return err
+}
+
+// exportHeader returns a tar header with no owner and a fixed modification time
+func exportHeader(name string, typeflag byte, mode, size int64) *tar.Header {
+	return &tar.Header{
+		Name:     name,
+		Typeflag: typeflag,
+		Mode:     mode,
+		Size:     size,
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+}
+
+// readArchiveInfo parses the metadata entry of an exported archive
+func readArchiveInfo(r io.Reader) (*ArchiveInfo, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveInfoSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ArchiveInfoFile, err)
+	}
+	info := &ArchiveInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ArchiveInfoFile, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,131 @@
+package templates
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+)
+
+func TestExport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		t.Fatalf("GetUserConfigDir() unexpected error: %v", err)
+	}
+	templateDir := filepath.Join(configDir, "templates", "contributed", "shared")
+	for rel, mode := range map[string]os.FileMode{"README.md": 0o600, "src/run.sh": 0o700, "src/._run.sh": 0o644, "z.txt": 0o644} {
+		full := filepath.Join(templateDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("# "+rel+"\n"), mode); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+	os.Symlink("README.md", filepath.Join(templateDir, "inside"))
+	os.Symlink("../../outside", filepath.Join(templateDir, "escape"))
+
+	outDir := t.TempDir()
+	first := filepath.Join(outDir, "first.tar.gz")
+	info, err := Export("shared", first, "v1.2.3")
+	if err != nil {
+		t.Fatalf("Export() unexpected error: %v", err)
+	}
+	if info.Files != 3 || info.ToolVersion != "v1.2.3" {
+		t.Errorf("Export() = %+v, want 3 files from v1.2.3", info)
+	}
+
+	t.Run("reproducible", func(t *testing.T) {
+		later := time.Now().Add(time.Hour)
+		os.Chtimes(filepath.Join(templateDir, "README.md"), later, later)
+		second := filepath.Join(outDir, "second.tar.gz")
+		if _, err := Export("shared", second, "v1.2.3"); err != nil {
+			t.Fatalf("Export() unexpected error: %v", err)
+		}
+		a, _ := os.ReadFile(first)
+		b, _ := os.ReadFile(second)
+		if !bytes.Equal(a, b) {
+			t.Errorf("Export() twice gave different archives")
+		}
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		f, err := os.Open(first)
+		if err != nil {
+			t.Fatalf("Failed to open archive: %v", err)
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() unexpected error: %v", err)
+		}
+		tr := tar.NewReader(gz)
+		var names []string
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() unexpected error: %v", err)
+			}
+			names = append(names, header.Name)
+			if header.Uid != 0 || header.Uname != "" || header.ModTime.Unix() != 0 {
+				t.Errorf("%s has owner %d/%q and time %v, want them cleared", header.Name, header.Uid, header.Uname, header.ModTime)
+			}
+			if header.Name == "shared/src/run.sh" && header.Mode != 0o755 || header.Name == "shared/README.md" && header.Mode != 0o644 {
+				t.Errorf("%s has mode %o", header.Name, header.Mode)
+			}
+		}
+		want := []string{ArchiveInfoFile, "shared/", "shared/README.md", "shared/inside", "shared/src/", "shared/src/run.sh", "shared/z.txt"}
+		if !slices.Equal(names, want) {
+			t.Errorf("archive entries = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("install", func(t *testing.T) {
+		name, err := Install(first, "copied")
+		if err != nil || name != "copied" {
+			t.Fatalf("Install() = %q, %v, want copied", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(installedDir(t, "copied"), ArchiveInfoFile)); err == nil {
+			t.Errorf("Install() extracted %s", ArchiveInfoFile)
+		}
+
+		// An archive whose files no longer match its metadata is refused
+		tampered := filepath.Join(outDir, "tampered.tar.gz")
+		buf := makeTarGz(t, []entry{
+			{name: ArchiveInfoFile, typeflag: tar.TypeReg, body: `{"name":"tampered","digest":"sha256:0000"}`},
+			{name: "tampered/README.md", typeflag: tar.TypeReg, body: "changed\n"},
+		})
+		os.WriteFile(tampered, buf.Bytes(), 0o644)
+		if _, err := Install(tampered, ""); err == nil || !strings.Contains(err.Error(), "do not match") {
+			t.Errorf("Install() of a modified archive error = %v, want do not match", err)
+		}
+	})
+
+	t.Run("system templates", func(t *testing.T) {
+		data, _ := os.ReadFile(first)
+		embedded := fstest.MapFS{"templates/system/shared.tar.gz": {Data: data}}
+		if err := InitializeSystemTemplates(embedded, "v9.9.9"); err != nil {
+			t.Fatalf("InitializeSystemTemplates() unexpected error: %v", err)
+		}
+		systemDir := filepath.Join(configDir, "templates", "system")
+		if _, err := os.Stat(filepath.Join(systemDir, "shared", "src", "run.sh")); err != nil {
+			t.Errorf("InitializeSystemTemplates() did not extract the template: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(systemDir, ArchiveInfoFile)); err == nil {
+			t.Errorf("InitializeSystemTemplates() extracted %s", ArchiveInfoFile)
+		}
+	})
+}
@@ -0,0 +1,474 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/lint"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+)
+
+// sourcesFile lists where each installed contributed template came from, relative to
+// the templates directory
+const sourcesFile = "sources.json"
+
+// Kinds of template source
+const (
+	SourceGit       = "git"
+	SourceArchive   = "archive"
+	SourceDirectory = "directory"
+)
+
+// Source records where a contributed template was installed from, so it can be updated
+type Source struct {
+	Source    string    `json:"source"`
+	Kind      string    `json:"kind"`
+	Revision  string    `json:"revision"` // the commit for git sources, a digest of the files otherwise
+	Installed time.Time `json:"installed"`
+}
+
+// scpLike matches git's user@host:path shorthand for ssh remotes
+var scpLike = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// Install adds the template at source to the contributed templates under name, or under
+// a name taken from the source if name is empty. The source can be a .tar.gz, .tgz or
+// .zip file, a directory, or a git repository URL, including file:// URLs and bare
+// repositories on disk. A template whose manifest declares a version is installed as
+// name@version, alongside any other versions. It returns the name and version the
+// template was installed as.
+func Install(source, name string) (string, error) {
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	templatesDir := filepath.Join(configDir, "templates")
+	contributedDir := filepath.Join(templatesDir, "contributed")
+	if err := os.MkdirAll(contributedDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create contributed templates directory %s: %w", contributedDir, err)
+	}
+
+	if source, err = normalizeSource(source); err != nil {
+		return "", err
+	}
+	workDir, err := os.MkdirTemp(templatesDir, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	root, src, err := fetch(source, workDir)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = sourceName(source, root, workDir)
+		if !config.IsValidTemplateName(name) {
+			return "", fmt.Errorf("cannot name the template '%s' after its source - choose a name with --name", name)
+		}
+	}
+
+	m, err := validate(root)
+	if err != nil {
+		return "", err
+	}
+	ref := versionedName(name, m)
+	destDir := filepath.Join(contributedDir, ref)
+	if _, err := os.Stat(destDir); err == nil {
+		return "", fmt.Errorf("template '%s' is already installed - use 'template update %s' or --remove it first", ref, ref)
+	}
+	if err := os.Rename(root, destDir); err != nil {
+		return "", fmt.Errorf("failed to install template at %s: %w", destDir, err)
+	}
+	return ref, saveSource(templatesDir, ref, src)
+}
+
+// Update fetches the source the contributed template ref was installed from again and,
+// if the source has changed, installs what it now holds. A new version is installed
+// alongside the one it was fetched for; otherwise that version is replaced. Update
+// returns the name and version of the template it fetched or installed, and whether it
+// installed anything.
+func Update(ref string) (string, bool, error) {
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return "", false, err
+	}
+	templatesDir := filepath.Join(configDir, "templates")
+	contributedDir := filepath.Join(templatesDir, "contributed")
+	name, version := config.SplitTemplateRef(ref)
+	current, ok := findVersion(versionsOf(contributedDir, name), version)
+	if !ok {
+		return "", false, fmt.Errorf("template '%s' not found in contributed templates", ref)
+	}
+	ref = filepath.Base(current.dir)
+
+	sources, err := loadSources(templatesDir)
+	if err != nil {
+		return "", false, err
+	}
+	old, ok := sources[ref]
+	if !ok {
+		return "", false, fmt.Errorf("template '%s' was not installed with 'template install', so there is nothing to update it from", ref)
+	}
+
+	workDir, err := os.MkdirTemp(templatesDir, ".install-")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	root, src, err := fetch(old.Source, workDir)
+	if err != nil {
+		return "", false, err
+	}
+	if src.Revision == old.Revision {
+		return ref, false, nil
+	}
+	m, err := validate(root)
+	if err != nil {
+		return "", false, err
+	}
+	ref = versionedName(name, m)
+	destDir := filepath.Join(contributedDir, ref)
+	if _, err := os.Stat(destDir); os.IsNotExist(err) {
+		if err := os.Rename(root, destDir); err != nil {
+			return "", false, fmt.Errorf("failed to install template at %s: %w", destDir, err)
+		}
+		return ref, true, saveSource(templatesDir, ref, src)
+	}
+
+	// Swap the new copy in, putting the old one back if that fails
+	previous := filepath.Join(workDir, "previous")
+	if err := os.Rename(destDir, previous); err != nil {
+		return "", false, fmt.Errorf("failed to replace template at %s: %w", destDir, err)
+	}
+	if err := os.Rename(root, destDir); err != nil {
+		os.Rename(previous, destDir)
+		return "", false, fmt.Errorf("failed to replace template at %s: %w", destDir, err)
+	}
+	return ref, true, saveSource(templatesDir, ref, src)
+}
+
+// versionedName returns the directory a template called name is installed in: name, or
+// name@version if its manifest declares a version
+func versionedName(name string, m *manifest.Manifest) string {
+	if m.Version == "" {
+		return name
+	}
+	return name + "@" + m.Version
+}
+
+// InstalledSource returns the source the contributed template name, or name@version for
+// a versioned one, was installed from, or nil if it was not installed with Install
+func InstalledSource(name string) *Source {
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return nil
+	}
+	sources, err := loadSources(filepath.Join(configDir, "templates"))
+	if err != nil {
+		return nil
+	}
+	if src, ok := sources[name]; ok {
+		return &src
+	}
+	return nil
+}
+
+// normalizeSource makes a local source path absolute so the template can be updated from
+// any directory
+func normalizeSource(source string) (string, error) {
+	if strings.Contains(source, "://") || scpLike.MatchString(source) {
+		return source, nil
+	}
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return "", fmt.Errorf("invalid template source %s: %w", source, err)
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("template source %s not found", source)
+	}
+	return abs, nil
+}
+
+// fetch copies the template at source into workDir and returns the directory holding it.
+// An archive whose only entry is a folder holds the template inside that folder.
+func fetch(source, workDir string) (string, Source, error) {
+	src := Source{Source: source, Installed: time.Now()}
+	filesDir := filepath.Join(workDir, "files")
+
+	var err error
+	var info *ArchiveInfo
+	lower := strings.ToLower(source)
+	archivePath := strings.TrimPrefix(source, "file://")
+	switch {
+	case isGitSource(source):
+		src.Kind = SourceGit
+		src.Revision, err = cloneGit(source, filesDir)
+	case isArchive(lower) && strings.Contains(archivePath, "://"):
+		return "", src, fmt.Errorf("cannot install the remote archive %s - download it and install the file instead", source)
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		src.Kind = SourceArchive
+		info, err = extractFile(archivePath, filesDir)
+	case strings.HasSuffix(lower, ".zip"):
+		src.Kind = SourceArchive
+		err = extractZip(archivePath, filesDir)
+	default:
+		stat, statErr := os.Stat(source)
+		if statErr != nil || !stat.IsDir() {
+			return "", src, fmt.Errorf("unsupported template source %s (expected a .tar.gz or .zip file, a directory or a git repository)", source)
+		}
+		src.Kind = SourceDirectory
+		err = copyDir(source, filesDir)
+	}
+	if err != nil {
+		return "", src, err
+	}
+
+	root := filesDir
+	if src.Kind == SourceArchive {
+		if entries, err := os.ReadDir(filesDir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+			root = filepath.Join(filesDir, entries[0].Name())
+		}
+	}
+	if src.Kind != SourceGit {
+		if src.Revision, err = treeDigest(root); err != nil {
+			return "", src, err
+		}
+	}
+	if info != nil && info.Digest != src.Revision {
+		return "", src, fmt.Errorf("the files in %s do not match its %s; the archive is corrupt or was modified after export", source, ArchiveInfoFile)
+	}
+	return root, src, nil
+}
+
+// isGitSource reports whether source names a git repository rather than a file or an
+// ordinary directory. Archives are never repositories; otherwise only a .git suffix, a
+// git@, ssh:// or git:// prefix, an explicit git+ scheme or a bare repository on disk,
+// given as a path or a file:// URL, counts, so an https URL to anything else is not
+// cloned.
+func isGitSource(source string) bool {
+	lower := strings.ToLower(source)
+	if isArchive(lower) {
+		return false
+	}
+	for _, prefix := range []string{"git+", "git@", "ssh://", "git://"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	if strings.HasSuffix(strings.TrimRight(lower, "/"), ".git") {
+		return true
+	}
+	// A bare repository, for example on a shared drive
+	dir := strings.TrimPrefix(source, "file://")
+	_, headErr := os.Stat(filepath.Join(dir, "HEAD"))
+	_, objectsErr := os.Stat(filepath.Join(dir, "objects"))
+	return headErr == nil && objectsErr == nil
+}
+
+// isArchive reports whether the lower-cased source names a .tar.gz, .tgz or .zip file
+func isArchive(lower string) bool {
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneGit clones the latest revision of the repository at source into destDir and
+// returns the commit it holds. A git+ scheme is dropped before cloning. The clone's .git
+// directory is removed.
+func cloneGit(source, destDir string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is required to install a template from %s: %w", source, err)
+	}
+	url := source
+	if strings.HasPrefix(strings.ToLower(url), "git+") {
+		url = url[len("git+"):]
+	}
+	out, err := exec.Command("git", "clone", "--quiet", "--depth", "1", "--", url, destDir).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w\n%s", source, err, strings.TrimSpace(string(out)))
+	}
+	out, err = exec.Command("git", "-C", destDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the revision of %s: %w", source, err)
+	}
+	if err := os.RemoveAll(filepath.Join(destDir, ".git")); err != nil {
+		return "", fmt.Errorf("failed to clean up clone of %s: %w", source, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// extractFile extracts the .tar.gz file at archivePath into destDir and returns its
+// metadata if it was made by Export
+func extractFile(archivePath, destDir string) (*ArchiveInfo, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", archivePath, err)
+	}
+	defer f.Close()
+	info, err := extractTarGz(f, destDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", archivePath, err)
+	}
+	return info, nil
+}
+
+// sourceName derives a template name: the folder an archive holds the template in, or
+// the last element of the source without its extension
+func sourceName(source, root, workDir string) string {
+	if root != filepath.Join(workDir, "files") {
+		return filepath.Base(root)
+	}
+	name := path.Base(strings.TrimRight(filepath.ToSlash(source), "/"))
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip", ".git"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// validate checks that the directory at root holds a usable template: at least one file,
+// unless it extends another template, which must be installed, and, if it has one, a
+// valid manifest, which it returns combined with those of the templates it extends. Lint
+// problems are reported as warnings.
+func validate(root string) (*manifest.Manifest, error) {
+	layers, err := Layers(root)
+	if err != nil {
+		return nil, err
+	}
+	hasFiles := len(layers) > 1
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && d.Name() != manifest.FileName {
+			hasFiles = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if !hasFiles {
+		return nil, fmt.Errorf("the source holds no template files")
+	}
+
+	m, err := LoadManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := lint.Check(layers, m, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		fmt.Println("Warning:", issue)
+	}
+	return m, nil
+}
+
+// treeEntry is a file, directory or symbolic link below the root of a template
+type treeEntry struct {
+	rel  string // slash-separated path below the root
+	path string // path on disk
+	mode fs.FileMode
+}
+
+// treeDigest returns a digest of the paths and contents of every file and symbolic link
+// below root. macOS resource forks are left out, as they are from archives.
+func treeDigest(root string) (string, error) {
+	var entries []treeEntry
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(root, path)
+		rel := filepath.ToSlash(relPath)
+		if isResourceFork(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		entries = append(entries, treeEntry{rel, path, d.Type()})
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	return digestEntries(entries)
+}
+
+// digestEntries returns a digest of the paths and contents of the files and symbolic
+// links among entries, in the order given. Other entries are ignored.
+func digestEntries(entries []treeEntry) (string, error) {
+	h := sha256.New()
+	for _, e := range entries {
+		switch {
+		case e.mode&fs.ModeSymlink != 0:
+			target, err := os.Readlink(e.path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%s\x00-> %s\x00", e.rel, target)
+		case e.mode.IsRegular():
+			data, err := os.ReadFile(e.path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", e.rel, len(data))
+			h.Write(data)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadSources reads the recorded sources of installed templates
+func loadSources(templatesDir string) (map[string]Source, error) {
+	sources := make(map[string]Source)
+	data, err := os.ReadFile(filepath.Join(templatesDir, sourcesFile))
+	if os.IsNotExist(err) {
+		return sources, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template sources: %w", err)
+	}
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to parse template sources: %w", err)
+	}
+	return sources, nil
+}
+
+// saveSource records src as the source of template name, or forgets it if src is empty
+func saveSource(templatesDir, name string, src Source) error {
+	sources, err := loadSources(templatesDir)
+	if err != nil {
+		return err
+	}
+	if src == (Source{}) {
+		delete(sources, name)
+	} else {
+		sources[name] = src
+	}
+	data, err := json.MarshalIndent(sources, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode template sources: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(templatesDir, sourcesFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write template sources: %w", err)
+	}
+	return nil
+}
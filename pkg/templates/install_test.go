@@ -0,0 +1,223 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestInstall(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	installed := func(name, rel string) string {
+		data, err := os.ReadFile(filepath.Join(installedDir(t, name), filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("installed template %s is missing %s: %v", name, rel, err)
+		}
+		return string(data)
+	}
+	sources := t.TempDir()
+
+	t.Run("archive with a top folder", func(t *testing.T) {
+		archive := filepath.Join(sources, "packed-1.0.tar.gz")
+		buf := makeTarGz(t, []entry{
+			{name: "packed/", typeflag: tar.TypeDir},
+			{name: "packed/README.md", typeflag: tar.TypeReg, body: "# {{PROJECT_NAME}}\n"},
+		})
+		if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("Failed to write archive: %v", err)
+		}
+
+		name, err := Install(archive, "")
+		if err != nil || name != "packed" {
+			t.Fatalf("Install() = %q, %v, want packed", name, err)
+		}
+		if got := installed("packed", "README.md"); got != "# {{PROJECT_NAME}}\n" {
+			t.Errorf("README.md = %q", got)
+		}
+		if _, err := Install(archive, ""); err == nil || !strings.Contains(err.Error(), "already installed") {
+			t.Errorf("Install() again error = %v, want already installed", err)
+		}
+		if _, updated, err := Update("packed"); err != nil || updated {
+			t.Errorf("Update() of an unchanged archive = %v, %v, want false", updated, err)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		archive := filepath.Join(sources, "zipped.zip")
+		f, err := os.Create(archive)
+		if err != nil {
+			t.Fatalf("Failed to create archive: %v", err)
+		}
+		zw := zip.NewWriter(f)
+		for name, body := range map[string]string{"main.go": "package main\n", "src/app.go": "package src\n"} {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatalf("Create(%s) unexpected error: %v", name, err)
+			}
+			w.Write([]byte(body))
+		}
+		zw.Close()
+		f.Close()
+
+		name, err := Install(archive, "renamed")
+		if err != nil || name != "renamed" {
+			t.Fatalf("Install() = %q, %v, want renamed", name, err)
+		}
+		if got := installed("renamed", "src/app.go"); got != "package src\n" {
+			t.Errorf("src/app.go = %q", got)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := filepath.Join(sources, "plain")
+		testutil.WriteFiles(t, dir, map[string]string{"README.md": "v1\n", ".git/HEAD": "ref: refs/heads/main\n"})
+
+		if _, err := Install(dir, ""); err != nil {
+			t.Fatalf("Install() unexpected error: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(installedDir(t, "plain"), ".git")); err == nil {
+			t.Errorf("Install() copied the .git directory")
+		}
+
+		testutil.WriteFiles(t, dir, map[string]string{"README.md": "v2\n"})
+		if _, updated, err := Update("plain"); err != nil || !updated {
+			t.Fatalf("Update() = %v, %v, want true", updated, err)
+		}
+		if got := installed("plain", "README.md"); got != "v2\n" {
+			t.Errorf("README.md after Update() = %q, want v2", got)
+		}
+		if got := Version(installedDir(t, "plain")); !strings.HasPrefix(got, "sha256:") {
+			t.Errorf("Version() = %q, want the source digest", got)
+		}
+	})
+
+	t.Run("versions side by side", func(t *testing.T) {
+		dir := filepath.Join(sources, "versioned")
+		testutil.WriteFiles(t, dir, map[string]string{"README.md": "v1\n", manifest.FileName: `{"version": "1.0"}`})
+		if name, err := Install(dir, ""); err != nil || name != "versioned@1.0" {
+			t.Fatalf("Install() = %q, %v, want versioned@1.0", name, err)
+		}
+
+		testutil.WriteFiles(t, dir, map[string]string{"README.md": "v2\n", manifest.FileName: `{"version": "1.1"}`})
+		if ref, updated, err := Update("versioned"); err != nil || !updated || ref != "versioned@1.1" {
+			t.Fatalf("Update() = %q, %v, %v, want versioned@1.1", ref, updated, err)
+		}
+		if got := installed("versioned@1.0", "README.md"); got != "v1\n" {
+			t.Errorf("README.md of 1.0 after Update() = %q, want it kept", got)
+		}
+		if got := installed("versioned", "README.md"); got != "v2\n" {
+			t.Errorf("README.md of the newest version = %q, want v2", got)
+		}
+	})
+
+	t.Run("bare git repository", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		work := filepath.Join(sources, "work")
+		bare := filepath.Join(sources, "shared", "gitted.git")
+		git := func(dir string, args ...string) {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, out)
+			}
+		}
+		testutil.WriteFiles(t, work, map[string]string{"README.md": "first\n"})
+		git(work, "init", "--quiet")
+		git(work, "add", ".")
+		git(work, "commit", "--quiet", "-m", "first")
+		git(sources, "clone", "--quiet", "--bare", work, bare)
+
+		if name, err := Install("file://"+bare, ""); err != nil || name != "gitted" {
+			t.Fatalf("Install() = %q, %v, want gitted", name, err)
+		}
+		if src := InstalledSource("gitted"); src == nil || src.Kind != SourceGit || len(src.Revision) != 40 {
+			t.Errorf("InstalledSource() = %+v, want a git source with a commit", src)
+		}
+		if name, err := Install("git+file://"+bare, "gitted-plus"); err != nil || name != "gitted-plus" {
+			t.Errorf("Install() with a git+ scheme = %q, %v, want gitted-plus", name, err)
+		}
+
+		testutil.WriteFiles(t, work, map[string]string{"README.md": "second\n"})
+		git(work, "commit", "--quiet", "-am", "second")
+		git(work, "push", "--quiet", bare, "HEAD")
+		if _, updated, err := Update("gitted"); err != nil || !updated {
+			t.Fatalf("Update() = %v, %v, want true", updated, err)
+		}
+		if got := installed("gitted", "README.md"); got != "second\n" {
+			t.Errorf("README.md after Update() = %q, want second", got)
+		}
+	})
+
+	t.Run("invalid sources", func(t *testing.T) {
+		empty := filepath.Join(sources, "empty")
+		os.MkdirAll(empty, 0o755)
+		if _, err := Install(empty, ""); err == nil || !strings.Contains(err.Error(), "no template files") {
+			t.Errorf("Install() of an empty directory error = %v, want no template files", err)
+		}
+		file := filepath.Join(sources, "notes.txt")
+		testutil.WriteFiles(t, sources, map[string]string{"notes.txt": "hello"})
+		if _, err := Install(file, ""); err == nil || !strings.Contains(err.Error(), "unsupported template source") {
+			t.Errorf("Install() of a text file error = %v, want unsupported", err)
+		}
+		if _, err := Install("https://example.com/my-template.tar.gz", ""); err == nil || !strings.Contains(err.Error(), "cannot install the remote archive") {
+			t.Errorf("Install() of a remote archive error = %v, want it refused", err)
+		}
+		if _, _, err := Update("no-such-template"); err == nil {
+			t.Errorf("Update() of a missing template expected an error")
+		}
+	})
+}
+
+// installedDir returns the directory of an installed template
+func installedDir(t *testing.T, name string) string {
+	t.Helper()
+	dir, err := GetTemplateDir(name)
+	if err != nil {
+		t.Fatalf("GetTemplateDir(%s) unexpected error: %v", name, err)
+	}
+	return dir
+}
+
+func TestIsGitSource(t *testing.T) {
+	bare := t.TempDir()
+	for _, name := range []string{"HEAD", "objects"} {
+		if err := os.Mkdir(filepath.Join(bare, name), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"https://github.com/acme/my-template.git", true},
+		{"file:///mnt/shared/my-template.git/", true},
+		{"git@github.com:acme/my-template", true},
+		{"ssh://git@example.com/acme/my-template", true},
+		{"git://example.com/acme/my-template", true},
+		{"git+https://example.com/acme/my-template", true},
+		{"git+file:///mnt/shared/my-template", true},
+		{bare, true},
+		{"file://" + bare, true},
+		{"https://example.com/my-template.tar.gz", false},
+		{"https://example.com/my-template.tgz", false},
+		{"ssh://example.com/my-template.zip", false},
+		{"https://example.com/acme/my-template", false},
+		{"templates/my-template", false},
+	}
+	for _, tt := range tests {
+		if got := isGitSource(tt.source); got != tt.want {
+			t.Errorf("isGitSource(%s) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package templates
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/overlay"
+)
+
+// Layers returns the directories that make up the template at templateDir, base first:
+// the template itself if it extends nothing, or else the layers of the template it
+// extends with templateDir on top. Parents are looked up with GetTemplateDir when the
+// template is used, so an overlay picks up changes to its parent.
+func Layers(templateDir string) ([]overlay.Layer, error) {
+	var layers []overlay.Layer
+	chain := []string{filepath.Base(templateDir)}
+	seen := make(map[string]bool)
+	for dir := templateDir; ; {
+		seen[dir] = true
+		m, err := manifest.Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, overlay.Layer{Dir: dir, Deleted: m.Deleted})
+		if m.Extends == "" {
+			break
+		}
+
+		parentDir, err := GetTemplateDir(m.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("template %s extends %s: %w", filepath.Base(dir), m.Extends, err)
+		}
+		chain = append(chain, filepath.Base(parentDir))
+		if seen[parentDir] {
+			return nil, fmt.Errorf("template %s extends itself: %s", filepath.Base(templateDir), strings.Join(chain, " -> "))
+		}
+		dir = parentDir
+	}
+	slices.Reverse(layers)
+	return layers, nil
+}
+
+// LoadManifest returns the manifest of the template at templateDir, combined with the
+// manifests of the templates it extends
+func LoadManifest(templateDir string) (*manifest.Manifest, error) {
+	layers, err := Layers(templateDir)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, len(layers))
+	for i, layer := range layers {
+		dirs[i] = layer.Dir
+	}
+	return manifest.LoadLayers(dirs...)
+}
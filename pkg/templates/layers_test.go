@@ -0,0 +1,87 @@
+package templates
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+	"github.com/TrueBlocks/create-local-app/pkg/testutil"
+)
+
+func TestLayers(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		t.Fatalf("GetUserConfigDir() unexpected error: %v", err)
+	}
+	contributedDir := filepath.Join(configDir, "templates", "contributed")
+	for name, data := range map[string]string{
+		"base":   `{"name": "Base", "author": "Upstream", "variables": [{"name": "CHAIN", "prompt": "Chain"}]}`,
+		"middle": `{"extends": "base", "deleted": ["docs"], "author": "Middle"}`,
+		"top":    `{"extends": "middle", "version": "2.0", "deleted": ["LICENSE"]}`,
+		"loop-a": `{"extends": "loop-b"}`,
+		"loop-b": `{"extends": "loop-a"}`,
+		"orphan": `{"extends": "missing"}`,
+	} {
+		testutil.WriteFiles(t, filepath.Join(contributedDir, name), map[string]string{manifest.FileName: data})
+	}
+
+	t.Run("resolve", func(t *testing.T) {
+		layers, err := Layers(filepath.Join(contributedDir, "top"))
+		if err != nil {
+			t.Fatalf("Layers() unexpected error: %v", err)
+		}
+		var names []string
+		for _, layer := range layers {
+			names = append(names, filepath.Base(layer.Dir)+strings.Join(layer.Deleted, ","))
+		}
+		if got := strings.Join(names, " "); got != "base middledocs topLICENSE" {
+			t.Errorf("Layers() = %s, want base, middle and top with their deleted paths", got)
+		}
+
+		m, err := LoadManifest(filepath.Join(contributedDir, "top"))
+		if err != nil {
+			t.Fatalf("LoadManifest() unexpected error: %v", err)
+		}
+		if m.Name != "Base" || m.Author != "Middle" || m.Version != "2.0" || m.Extends != "middle" || len(m.Variables) != 1 {
+			t.Errorf("LoadManifest() = %+v, want the entries of every layer with the version of the top one", m)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			wantErr string
+		}{
+			{"loop-a", "template loop-a extends itself: loop-a -> loop-b -> loop-a"},
+			{"orphan", "template orphan extends missing: template 'missing' not found"},
+		}
+		for _, tt := range tests {
+			if _, err := Layers(filepath.Join(contributedDir, tt.name)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Layers(%s) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		if _, _, err := CreateDir("base", "base", false, false); err == nil || !strings.Contains(err.Error(), "cannot extend itself") {
+			t.Errorf("CreateDir() extending itself error = %v", err)
+		}
+		if _, _, err := CreateDir("new", "missing", false, false); err == nil || !strings.Contains(err.Error(), "cannot extend missing") {
+			t.Errorf("CreateDir() extending a missing template error = %v", err)
+		}
+
+		dir, manifestDir, err := CreateDir("new", "base", true, false)
+		if err != nil || manifestDir != filepath.Join(contributedDir, "base") {
+			t.Errorf("CreateDir() dry run = %s, %v, want the manifest of base", manifestDir, err)
+		}
+		if _, manifestDir, err = CreateDir("new", "base", false, false); err != nil || manifestDir != dir {
+			t.Fatalf("CreateDir() = %s, %v, want %s", manifestDir, err, dir)
+		}
+		if m, err := LoadManifest(dir); err != nil || m.Extends != "base" || m.Name != "Base" {
+			t.Errorf("LoadManifest() of the new overlay = %+v, %v, want it to extend base", m, err)
+		}
+	})
+}
@@ -1,11 +1,8 @@
 package templates
 
 import (
-	"archive/tar"
-	"compress/gzip"
-	"embed"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,25 +11,31 @@ import (
 )
 
 // GetTemplateDir returns the path to a template directory
-// It looks first in ~/.create-local-app/templates/contributed/templateName, then ~/.create-local-app/templates/system/templateName
+// It looks first in ~/.create-local-app/templates/contributed, then ~/.create-local-app/templates/system,
+// and picks the newest version of the template unless templateName pins one, as in my-template@1.2
 func GetTemplateDir(templateName string) (string, error) {
 	configDir, err := config.GetUserConfigDir()
 	if err != nil {
 		return "", err
 	}
 
-	// Check contributed templates first
-	contributedPath := filepath.Join(configDir, "templates", "contributed", templateName)
-	if info, err := os.Stat(contributedPath); err == nil && info.IsDir() {
-		return contributedPath, nil
+	name, version := config.SplitTemplateRef(templateName)
+	var available []string
+	for _, kind := range []string{"contributed", "system"} {
+		versions := versionsOf(filepath.Join(configDir, "templates", kind), name)
+		if found, ok := findVersion(versions, version); ok {
+			return found.dir, nil
+		}
+		for _, v := range versions {
+			if v.version != "" {
+				available = append(available, v.version)
+			}
+		}
 	}
 
-	// Check system templates
-	systemPath := filepath.Join(configDir, "templates", "system", templateName)
-	if info, err := os.Stat(systemPath); err == nil && info.IsDir() {
-		return systemPath, nil
+	if len(available) > 0 {
+		return "", fmt.Errorf("template '%s' has no version %s (available: %s)", name, version, strings.Join(available, ", "))
 	}
-
 	return "", fmt.Errorf("template '%s' not found in contributed or system templates", templateName)
 }
 
@@ -51,9 +54,38 @@ func GetDefaultTemplateDir() (string, error) {
 	return defaultPath, nil
 }
 
+// Version returns the version of the template at templateDir: the version it declares,
+// if any. Otherwise system templates carry the version of the release that installed
+// them and installed contributed templates the revision of their source; other templates
+// have none.
+func Version(templateDir string) string {
+	if version := declaredVersion(templateDir); version != "" {
+		return version
+	}
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return ""
+	}
+	if filepath.Dir(templateDir) == filepath.Join(configDir, "templates", "contributed") {
+		if src := InstalledSource(filepath.Base(templateDir)); src != nil {
+			return src.Revision
+		}
+		return ""
+	}
+	rel, err := filepath.Rel(filepath.Join(configDir, "templates", "system"), templateDir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "VERSION"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // InitializeSystemTemplates extracts embedded system templates to the user config directory
 // It checks versions to determine if templates need to be updated
-func InitializeSystemTemplates(embeddedFS embed.FS, currentVersion string) error {
+func InitializeSystemTemplates(embeddedFS fs.FS, currentVersion string) error {
 	// Get user config directory for destination
 	configDir, err := config.GetUserConfigDir()
 	if err != nil {
@@ -100,14 +132,20 @@ func InitializeSystemTemplates(embeddedFS embed.FS, currentVersion string) error
 	}
 
 	// Extract each tar.gz file from the embedded filesystem
-	entries, err := embeddedFS.ReadDir("templates/system")
+	entries, err := fs.ReadDir(embeddedFS, "templates/system")
 	if err != nil {
 		return fmt.Errorf("failed to read embedded templates directory: %w", err)
 	}
 
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tar.gz") {
-			if err := extractTarGz(embeddedFS, "templates/system/"+entry.Name(), destTemplatesDir); err != nil {
+			tarFile, err := embeddedFS.Open("templates/system/" + entry.Name())
+			if err != nil {
+				return fmt.Errorf("failed to open embedded tar file %s: %w", entry.Name(), err)
+			}
+			_, err = extractTarGz(tarFile, destTemplatesDir)
+			tarFile.Close()
+			if err != nil {
 				return fmt.Errorf("failed to extract %s: %w", entry.Name(), err)
 			}
 		}
@@ -117,87 +155,6 @@ func InitializeSystemTemplates(embeddedFS embed.FS, currentVersion string) error
 	return nil
 }
 
-// extractTarGz extracts a tar.gz file from the embedded filesystem to the destination directory
-func extractTarGz(embeddedFS embed.FS, tarPath, destDir string) error {
-	// Open the embedded tar.gz file
-	tarFile, err := embeddedFS.Open(tarPath)
-	if err != nil {
-		return fmt.Errorf("failed to open embedded tar file %s: %w", tarPath, err)
-	}
-	defer tarFile.Close()
-
-	// Create gzip reader
-	gzReader, err := gzip.NewReader(tarFile)
-	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
-	}
-	defer gzReader.Close()
-
-	// Create tar reader
-	tarReader := tar.NewReader(gzReader)
-
-	// Extract files
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("failed to read tar header: %w", err)
-		}
-
-		// Skip macOS resource fork files (._filename)
-		if strings.Contains(header.Name, "/._") || strings.HasPrefix(filepath.Base(header.Name), "._") {
-			continue
-		}
-
-		// Create the full destination path
-		destPath := filepath.Join(destDir, header.Name)
-
-		// Handle different file types
-		switch header.Typeflag {
-		case tar.TypeDir:
-			// Create directory
-			if err := os.MkdirAll(destPath, os.FileMode(header.Mode)); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", destPath, err)
-			}
-		case tar.TypeReg:
-			// Extract regular file
-			if err := extractRegularFile(tarReader, destPath, header); err != nil {
-				return fmt.Errorf("failed to extract file %s: %w", destPath, err)
-			}
-		default:
-			// Skip other file types for now
-			continue
-		}
-	}
-
-	return nil
-}
-
-// extractRegularFile extracts a regular file from the tar reader
-func extractRegularFile(tarReader *tar.Reader, destPath string, header *tar.Header) error {
-	// Create destination directory if it doesn't exist
-	destDir := filepath.Dir(destPath)
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", destDir, err)
-	}
-
-	// Create the file
-	outFile, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
-	if err != nil {
-		return fmt.Errorf("failed to create file %s: %w", destPath, err)
-	}
-	defer outFile.Close()
-
-	// Copy file contents
-	if _, err := io.Copy(outFile, tarReader); err != nil {
-		return fmt.Errorf("failed to write file contents: %w", err)
-	}
-
-	return nil
-}
-
 // ListTemplates lists all available templates (system and contributed)
 func ListTemplates() error {
 	configDir, err := config.GetUserConfigDir()
@@ -231,7 +188,7 @@ func ListTemplates() error {
 	return nil
 }
 
-// listTemplatesInDir lists templates in a specific directory
+// listTemplatesInDir lists templates in a specific directory, with the versions installed
 func listTemplatesInDir(dir string) ([]string, error) {
 	var templates []string
 
@@ -244,31 +201,55 @@ func listTemplatesInDir(dir string) ([]string, error) {
 		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
 	}
 
+	seen := make(map[string]bool)
 	for _, entry := range entries {
-		if entry.IsDir() {
-			templates = append(templates, entry.Name())
+		name, _ := config.SplitTemplateRef(entry.Name())
+		if !entry.IsDir() || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var versions []string
+		for _, v := range versionsOf(dir, name) {
+			if v.version != "" {
+				versions = append(versions, v.version)
+			}
 		}
+		if len(versions) > 0 {
+			name += " (" + strings.Join(versions, ", ") + ")"
+		}
+		templates = append(templates, name)
 	}
 
 	return templates, nil
 }
 
-// HandleRemoveTemplate removes a contributed template with user confirmation
+// HandleRemoveTemplate removes a contributed template with user confirmation: every
+// version of it, or only the one templateName pins
 func HandleRemoveTemplate(templateName string) error {
 	configDir, err := config.GetUserConfigDir()
 	if err != nil {
 		return fmt.Errorf("error getting user config directory: %w", err)
 	}
 
-	templatePath := filepath.Join(configDir, "templates", "contributed", templateName)
-
 	// Check if template exists
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
+	name, version := config.SplitTemplateRef(templateName)
+	var templatePaths []string
+	for _, v := range versionsOf(filepath.Join(configDir, "templates", "contributed"), name) {
+		if version == "" || v.version == version {
+			templatePaths = append(templatePaths, v.dir)
+		}
+	}
+	if len(templatePaths) == 0 {
 		return fmt.Errorf("template '%s' not found in contributed templates", templateName)
 	}
 
 	// Ask for confirmation
-	fmt.Printf("Are you sure you want to remove template '%s'? This action cannot be undone. (y/N): ", templateName)
+	if len(templatePaths) > 1 {
+		fmt.Printf("Are you sure you want to remove all %d versions of template '%s'? This action cannot be undone. (y/N): ", len(templatePaths), templateName)
+	} else {
+		fmt.Printf("Are you sure you want to remove template '%s'? This action cannot be undone. (y/N): ", templateName)
+	}
 
 	var response string
 	fmt.Scanln(&response)
@@ -279,9 +260,14 @@ func HandleRemoveTemplate(templateName string) error {
 		return nil
 	}
 
-	// Remove the template directory
-	if err := os.RemoveAll(templatePath); err != nil {
-		return fmt.Errorf("error removing template: %w", err)
+	// Remove the template directories
+	for _, templatePath := range templatePaths {
+		if err := os.RemoveAll(templatePath); err != nil {
+			return fmt.Errorf("error removing template: %w", err)
+		}
+		if err := saveSource(filepath.Join(configDir, "templates"), filepath.Base(templatePath), Source{}); err != nil {
+			return err
+		}
 	}
 
 	fmt.Printf("✅ Template '%s' successfully removed from contributed templates.\n", templateName)
@@ -0,0 +1,216 @@
+package templates
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+)
+
+// installedVersion is one copy of a template in a templates directory
+type installedVersion struct {
+	dir     string
+	version string // empty if the copy declares no version
+}
+
+// versionsOf returns the copies of the template name in templatesDir, oldest first: a
+// directory called name, whose version is the one its manifest declares, and every
+// name@version directory. If the manifest of name declares a version that is also
+// installed as name@version, only the name@version directory is listed.
+func versionsOf(templatesDir, name string) []installedVersion {
+	entries, err := os.ReadDir(templatesDir)
+	if err != nil {
+		return nil
+	}
+	var versions []installedVersion
+	var unversioned string
+	for _, entry := range entries {
+		dirName, version := config.SplitTemplateRef(entry.Name())
+		if !entry.IsDir() || dirName != name {
+			continue
+		}
+		if version == "" {
+			unversioned = filepath.Join(templatesDir, entry.Name())
+			continue
+		}
+		versions = append(versions, installedVersion{filepath.Join(templatesDir, entry.Name()), version})
+	}
+	if unversioned != "" {
+		version := manifestVersion(unversioned)
+		if version == "" || !slices.ContainsFunc(versions, func(v installedVersion) bool { return v.version == version }) {
+			versions = append(versions, installedVersion{unversioned, version})
+		}
+	}
+	slices.SortStableFunc(versions, func(a, b installedVersion) int { return compareVersions(a.version, b.version) })
+	return versions
+}
+
+// findVersion returns the newest copy in versions, or the newest with the given version
+// if version is not empty
+func findVersion(versions []installedVersion, version string) (installedVersion, bool) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if version == "" || versions[i].version == version {
+			return versions[i], true
+		}
+	}
+	return installedVersion{}, false
+}
+
+// declaredVersion returns the version of the template at templateDir: the one in its
+// directory name, or else the one its manifest declares
+func declaredVersion(templateDir string) string {
+	if _, version := config.SplitTemplateRef(filepath.Base(templateDir)); version != "" {
+		return version
+	}
+	return manifestVersion(templateDir)
+}
+
+// manifestVersion returns the version declared by the manifest of the template at
+// templateDir, if it has one
+func manifestVersion(templateDir string) string {
+	m, err := manifest.Load(templateDir)
+	if err != nil {
+		return ""
+	}
+	return m.Version
+}
+
+// compareVersions orders two versions by their dot-separated parts, comparing parts that
+// are both numbers numerically, so 1.10 is newer than 1.9. A leading v is ignored and an
+// empty version is older than any other. As in semantic versioning, a pre-release such
+// as 2.0-beta is older than its release, 2.0.
+func compareVersions(a, b string) int {
+	if a == "" || b == "" {
+		return cmp.Compare(len(a), len(b))
+	}
+	aCore, aPre, aHasPre := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bCore, bPre, bHasPre := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	if c := compareParts(aCore, bCore); c != 0 {
+		return c
+	}
+	switch {
+	case aHasPre && bHasPre:
+		return compareParts(aPre, bPre)
+	case aHasPre:
+		return -1
+	case bHasPre:
+		return 1
+	}
+	return 0
+}
+
+// compareParts orders two dot-separated lists part by part. Parts that are both numbers
+// compare numerically and a number is older than a word, as semantic versioning orders
+// pre-release identifiers; otherwise parts compare as text, and a shorter list that
+// matches the start of a longer one is older.
+func compareParts(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(aNum, bNum)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(aParts[i], bParts[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aParts), len(bParts))
+}
+
+// CreateDir returns the contributed template directory that --create writes the template
+// ref to, and the directory whose manifest describes the template. A new version of a
+// template starts from the manifest of its newest version and the manifest records the
+// version; nothing is written if dryRun is set. Once a template has versions, --create
+// must name one. An existing template or version is only written over if force is set.
+// If extends is not empty the template becomes an overlay of that template, which must
+// exist.
+func CreateDir(ref, extends string, dryRun, force bool) (string, string, error) {
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	contributedDir := filepath.Join(configDir, "templates", "contributed")
+	dir := filepath.Join(contributedDir, ref)
+	name, version := config.SplitTemplateRef(ref)
+	versions := versionsOf(contributedDir, name)
+
+	parentDir := ""
+	if extends != "" {
+		if parentDir, err = GetTemplateDir(extends); err != nil {
+			return "", "", fmt.Errorf("template %s cannot extend %s: %w", ref, extends, err)
+		}
+		if parentDir == dir {
+			return "", "", fmt.Errorf("template %s cannot extend itself", ref)
+		}
+	}
+
+	manifestDir := dir
+	if version == "" {
+		var existing []string
+		for _, v := range versions {
+			if filepath.Base(v.dir) != name {
+				existing = append(existing, v.version)
+			}
+		}
+		if len(existing) > 0 {
+			return "", "", fmt.Errorf("template '%s' has versions %s - add a new one with --create %s@<version>", name, strings.Join(existing, ", "), name)
+		}
+	}
+	if _, err := os.Stat(dir); err == nil {
+		if !force {
+			return "", "", fmt.Errorf("template '%s' already exists - add a new version with --create %s@<version> or use --force to overwrite it", ref, name)
+		}
+	} else if version != "" && len(versions) > 0 {
+		manifestDir = versions[len(versions)-1].dir
+	}
+	if dryRun {
+		if _, err := os.Stat(filepath.Join(manifestDir, manifest.FileName)); os.IsNotExist(err) && parentDir != "" {
+			// The new overlay starts with the variables of the template it extends
+			manifestDir = parentDir
+		}
+		return dir, manifestDir, nil
+	}
+	if version == "" && extends == "" {
+		return dir, dir, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create template directory %s: %w", dir, err)
+	}
+	if manifestDir != dir {
+		data, err := os.ReadFile(filepath.Join(manifestDir, manifest.FileName))
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, manifest.FileName), data, 0644)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("failed to copy the manifest of %s: %w", manifestDir, err)
+		}
+	}
+	if version != "" {
+		if err := manifest.SetVersion(dir, version); err != nil {
+			return "", "", err
+		}
+	}
+	if extends != "" {
+		if err := manifest.SetExtends(dir, extends); err != nil {
+			return "", "", err
+		}
+	}
+	return dir, dir, nil
+}
@@ -0,0 +1,122 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/create-local-app/pkg/config"
+	"github.com/TrueBlocks/create-local-app/pkg/manifest"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2", "1.2", 0},
+		{"1.9", "1.10", -1},
+		{"v2.0", "1.5", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.0-beta", "1.0-rc", -1},
+		{"2.0.0-beta", "2.0.0", -1},
+		{"2.0.0", "2.0.0-beta", 1},
+		{"v2.0-rc.1", "2.0", -1},
+		{"2.0-rc.1", "1.9", 1},
+		{"2.0-rc.2", "2.0-rc.10", -1},
+		{"2.0-alpha", "2.0-alpha.1", -1},
+		{"2.0-1", "2.0-alpha", -1},
+		{"2.0-beta", "2.0-beta", 0},
+		{"", "0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetUserConfigDir()
+	if err != nil {
+		t.Fatalf("GetUserConfigDir() unexpected error: %v", err)
+	}
+	contributedDir := filepath.Join(configDir, "templates", "contributed")
+	for _, dir := range []string{"my-tpl", "my-tpl@1.2", "my-tpl@1.10", "other"} {
+		if err := os.MkdirAll(filepath.Join(contributedDir, dir), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	variables := `{"variables": [{"name": "CHAIN", "prompt": "Chain"}]}`
+	os.WriteFile(filepath.Join(contributedDir, "my-tpl@1.10", manifest.FileName), []byte(variables), 0o644)
+	// The unversioned copy declares a version that is also installed as my-tpl@1.2
+	os.WriteFile(filepath.Join(contributedDir, "my-tpl", manifest.FileName), []byte(`{"version": "1.2"}`), 0o644)
+
+	t.Run("resolve", func(t *testing.T) {
+		tests := []struct {
+			ref     string
+			want    string
+			wantErr string
+		}{
+			{ref: "my-tpl", want: "my-tpl@1.10"},
+			{ref: "my-tpl@1.2", want: "my-tpl@1.2"},
+			{ref: "other", want: "other"},
+			{ref: "my-tpl@3", wantErr: "has no version 3 (available: 1.2, 1.10)"},
+			{ref: "missing", wantErr: "not found"},
+		}
+		for _, tt := range tests {
+			dir, err := GetTemplateDir(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetTemplateDir(%s) error = %v, want %q", tt.ref, err, tt.wantErr)
+				}
+				continue
+			}
+			if err != nil || filepath.Base(dir) != tt.want {
+				t.Errorf("GetTemplateDir(%s) = %s, %v, want %s", tt.ref, dir, err, tt.want)
+			}
+		}
+		if got := Version(filepath.Join(contributedDir, "my-tpl@1.10")); got != "1.10" {
+			t.Errorf("Version() = %q, want 1.10", got)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		if _, _, err := CreateDir("my-tpl", "", false, false); err == nil || !strings.Contains(err.Error(), "--create my-tpl@<version>") {
+			t.Errorf("CreateDir() without a version error = %v, want a hint to add one", err)
+		}
+
+		dir, manifestDir, err := CreateDir("my-tpl@2.0", "", true, false)
+		if err != nil || filepath.Base(manifestDir) != "my-tpl@1.10" {
+			t.Fatalf("CreateDir() dry run = %s, %v, want the manifest of 1.10", manifestDir, err)
+		}
+		if _, err := os.Stat(dir); err == nil {
+			t.Errorf("CreateDir() dry run created %s", dir)
+		}
+
+		if _, manifestDir, err = CreateDir("my-tpl@2.0", "", false, false); err != nil || manifestDir != dir {
+			t.Fatalf("CreateDir() = %s, %v, want %s", manifestDir, err, dir)
+		}
+		if _, _, err := CreateDir("other", "", false, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("CreateDir() of an existing template error = %v, want it refused", err)
+		}
+		if _, manifestDir, err := CreateDir("other", "", false, true); err != nil || filepath.Base(manifestDir) != "other" {
+			t.Errorf("CreateDir() of an existing template with force = %s, %v, want other", manifestDir, err)
+		}
+		if _, _, err := CreateDir("my-tpl@2.0", "", false, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("CreateDir() of an installed version error = %v, want it refused", err)
+		}
+		if _, manifestDir, err := CreateDir("my-tpl@2.0", "", false, true); err != nil || manifestDir != dir {
+			t.Errorf("CreateDir() with force = %s, %v, want %s", manifestDir, err, dir)
+		}
+		m, err := manifest.Load(dir)
+		if err != nil || m.Version != "2.0" || m.Variables[0].Name != "CHAIN" {
+			t.Errorf("manifest of the new version = %+v, %v, want version 2.0 with the variables of 1.10", m, err)
+		}
+		if got, _ := GetTemplateDir("my-tpl"); got != dir {
+			t.Errorf("GetTemplateDir() = %s, want the new version %s", got, dir)
+		}
+	})
+}